@@ -0,0 +1,164 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator checks a bearer token and returns the identity it belongs
+// to.
+type Authenticator func(ctx context.Context, token string) (string, error)
+
+// StaticTokens authenticates fixed API keys, mapping each token to its
+// identity.
+func StaticTokens(tokens map[string]string) Authenticator {
+	return func(_ context.Context, token string) (string, error) {
+		for key, identity := range tokens {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+				return identity, nil
+			}
+		}
+		return "", errors.New("unknown token")
+	}
+}
+
+// JWTAuthenticator verifies JWTs locally with key: a []byte secret for the
+// HS algorithms or the public key for the RS, PS, ES and EdDSA ones. methods
+// lists the accepted signing algorithms, like "RS256". The tokens must
+// expire, and their subject is the identity.
+func JWTAuthenticator(key any, methods ...string) Authenticator {
+	parser := jwt.NewParser(jwt.WithValidMethods(methods), jwt.WithExpirationRequired())
+	return func(_ context.Context, token string) (string, error) {
+		claims := &jwt.RegisteredClaims{}
+		if _, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return key, nil }); err != nil {
+			return "", err
+		}
+		if claims.Subject == "" {
+			return "", errors.New("the token has no subject")
+		}
+		return claims.Subject, nil
+	}
+}
+
+// AccessPolicy maps identities to the directories under the server root
+// whose files they may read, like "exports/2024". "." allows the whole
+// root. Every identity may read the files it uploaded.
+type AccessPolicy map[string][]string
+
+// allows tells whether identity may read the file at rel, a local path
+// relative to the root.
+func (p AccessPolicy) allows(identity, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, dir := range p[identity] {
+		dir = filepath.ToSlash(filepath.Clean(dir))
+		if dir == "." || rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// identityFrom returns the authenticated identity of a request, or "" if
+// the server does not authenticate requests.
+func identityFrom(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
+
+// uploadOwnerDir returns the directory under the upload directory holding
+// the uploads of identity. Identities are hashed, since they are not
+// necessarily valid file names.
+func uploadOwnerDir(identity string) string {
+	if identity == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:16])
+}
+
+// authenticate checks the bearer token of a request and returns the context
+// with its identity.
+func (s *XlsxServer) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "the request carries no bearer token")
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "the request carries no bearer token")
+	}
+	identity, err := s.auth(ctx, token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if identity == "" {
+		return nil, status.Error(codes.Unauthenticated, "the token has no identity")
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+func (s *XlsxServer) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !serviceMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *XlsxServer) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !serviceMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize checks that the identity of the request may read the file at
+// rel, a local path relative to the root.
+func (s *XlsxServer) authorize(ctx context.Context, filePath, rel string) error {
+	if s.policy == nil {
+		return nil
+	}
+	if identity := identityFrom(ctx); !s.policy.allows(identity, rel) {
+		return status.Errorf(codes.PermissionDenied, "%s may not read %q", identity, filePath)
+	}
+	return nil
+}
+
+// BearerToken returns per-RPC credentials sending token as a bearer token.
+// They are only sent over TLS.
+func BearerToken(token string) credentials.PerRPCCredentials {
+	return bearerToken(token)
+}
+
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": fmt.Sprintf("Bearer %s", t)}, nil
+}
+
+func (bearerToken) RequireTransportSecurity() bool {
+	return true
+}
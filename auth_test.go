@@ -0,0 +1,119 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	policy := AccessPolicy{
+		"alice": {"exports/2024/"},
+		"bob":   {"."},
+	}
+	tests := []struct {
+		identity, rel string
+		want          bool
+	}{
+		{"alice", "exports/2024/march.csv", true},
+		{"alice", "exports/2024/q1/march.csv", true},
+		{"alice", "exports/2024-old/march.csv", false},
+		{"alice", "exports/march.csv", false},
+		{"bob", "exports/march.csv", true},
+		{"carol", "exports/2024/march.csv", false},
+	}
+	for _, tt := range tests {
+		if got := policy.allows(tt.identity, tt.rel); got != tt.want {
+			t.Errorf("allows(%q, %q) = %v, want %v", tt.identity, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, 0)
+	serverCert := newTestCert(t, dir, "server", ca, x509.ExtKeyUsageServerAuth)
+	root := filepath.Join(dir, "root")
+	for _, name := range []string{"a/calls.csv", "b/calls.csv"} {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("calls"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secret := []byte("secret")
+	tokens := StaticTokens(map[string]string{"alice-key": "alice"})
+	jwts := JWTAuthenticator(secret, "HS256")
+	auth := func(ctx context.Context, token string) (string, error) {
+		if identity, err := tokens(ctx, token); err == nil {
+			return identity, nil
+		}
+		return jwts(ctx, token)
+	}
+	srv := newTestServer(t, root,
+		WithTLS(TLSFiles{CertFile: serverCert.certFile, KeyFile: serverCert.keyFile}),
+		WithUploadDir(filepath.Join(dir, "uploads")),
+		WithAuth(auth, AccessPolicy{"alice": {"a"}, "bob": {"."}}))
+	dial := startTLSServer(t, srv)
+	clientTLS := &TLSFiles{CAFile: ca.certFile, ServerName: "localhost"}
+	client := func(token string) XlsxServiceClient {
+		var opts []grpc.DialOption
+		if token != "" {
+			opts = append(opts, grpc.WithPerRPCCredentials(BearerToken(token)))
+		}
+		return NewXlsxServiceClient(dial(clientTLS, opts...))
+	}
+	sign := func(claims jwt.RegisteredClaims, key []byte) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	expires := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	bob := sign(jwt.RegisteredClaims{Subject: "bob", ExpiresAt: expires}, secret)
+
+	tests := []struct {
+		name, token, path string
+		code              codes.Code
+	}{
+		{"no token", "", "a/calls.csv", codes.Unauthenticated},
+		{"unknown key", "mallory-key", "a/calls.csv", codes.Unauthenticated},
+		{"allowed directory", "alice-key", "a/calls.csv", codes.OK},
+		{"other directory", "alice-key", "b/calls.csv", codes.PermissionDenied},
+		{"jwt", bob, "b/calls.csv", codes.OK},
+		{"expired jwt", sign(jwt.RegisteredClaims{Subject: "bob", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}, secret), "b/calls.csv", codes.Unauthenticated},
+		{"jwt without expiry", sign(jwt.RegisteredClaims{Subject: "bob"}, secret), "b/calls.csv", codes.Unauthenticated},
+		{"jwt of another key", sign(jwt.RegisteredClaims{Subject: "bob", ExpiresAt: expires}, []byte("other")), "b/calls.csv", codes.Unauthenticated},
+		{"jwt of an unknown identity", sign(jwt.RegisteredClaims{Subject: "carol", ExpiresAt: expires}, secret), "a/calls.csv", codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		_, _, err := fetchFile(context.Background(), client(tt.token), tt.path)
+		if code := status.Code(unwrapAll(err)); code != tt.code {
+			t.Errorf("%s: error = %v, want code %s", tt.name, err, tt.code)
+		}
+	}
+
+	// Uploads are only visible to the identity that uploaded them.
+	id, err := (&FileClient{xlsxClient: client("alice-key")}).UploadFile(context.Background(), "test_file.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := fetchFile(context.Background(), client("alice-key"), id); err != nil {
+		t.Errorf("uploader cannot fetch the upload: %v", err)
+	}
+	if _, _, err := fetchFile(context.Background(), client(bob), id); status.Code(unwrapAll(err)) != codes.NotFound {
+		t.Errorf("another identity fetching the upload: error = %v, want NotFound", err)
+	}
+}
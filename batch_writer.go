@@ -0,0 +1,110 @@
+package dgraph_imei
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+
+	"github.com/dgraph-io/dgo/v230"
+)
+
+// defaultBatchSize is the number of calls sent to Dgraph in one upsert
+// request unless the client is configured otherwise.
+const defaultBatchSize = 1000
+
+// defaultWorkers is the number of batches written concurrently unless the
+// client is configured otherwise.
+const defaultWorkers = 1
+
+// batchWriter collects parsed calls and writes them to Dgraph in batches,
+// one batch per worker at a time. Calls are sharded by MSDIN: every call of
+// an account updates the account node, so writing them from one worker
+// keeps the workers from conflicting on it. Devices are shared between the
+// workers. IMEI and MSDIN are @upsert predicates, so two workers or clients
+// creating the same device or account conflict, and the aborted transaction
+// is retried and finds the node the other one created.
+//
+// The workers write their batches in rounds. A round ends when every batch
+// is committed, and only then is the import checkpoint advanced to the last
+// call of the round. A failed batch ends the ingestion after its round.
+type batchWriter struct {
+	size       int
+	shards     [][]*Call
+	checkpoint *importCheckpoint
+	// upsert writes a batch and store a checkpoint. Tests replace them to
+	// run without Dgraph.
+	upsert func(ctx context.Context, calls []*Call) error
+	store  func(ctx context.Context, cp *importCheckpoint) error
+}
+
+// newBatchWriter returns a writer linking every call to the import node imp,
+// which may be nil.
+func newBatchWriter(client *dgo.Dgraph, size, workers int, retry retryPolicy, imp *nquadNode) *batchWriter {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	w := &batchWriter{
+		size:   size,
+		shards: make([][]*Call, max(workers, 1)),
+		upsert: func(ctx context.Context, calls []*Call) error {
+			return upsertCalls(ctx, client, calls, imp, retry)
+		},
+		store: func(ctx context.Context, cp *importCheckpoint) error {
+			return storeCheckpoint(ctx, client, cp, retry)
+		},
+	}
+	for i := range w.shards {
+		w.shards[i] = make([]*Call, 0, size)
+	}
+	return w
+}
+
+// add queues a call and writes a round once a batch is full. cp tells how
+// far the import has got with this call and may be nil.
+func (w *batchWriter) add(ctx context.Context, call *Call, cp *importCheckpoint) error {
+	shard := &w.shards[shardOf(call.Msdin, len(w.shards))]
+	*shard = append(*shard, call)
+	w.checkpoint = cp
+	if len(*shard) < w.size {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// flush writes all queued calls, then stores the checkpoint of the last one.
+func (w *batchWriter) flush(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(w.shards))
+	for i, calls := range w.shards {
+		if len(calls) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w.upsert(ctx, calls)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for i := range w.shards {
+		w.shards[i] = w.shards[i][:0]
+	}
+	if w.checkpoint != nil {
+		if err := w.store(ctx, w.checkpoint); err != nil {
+			return err
+		}
+		w.checkpoint = nil
+	}
+	return nil
+}
+
+func shardOf(msdin string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(msdin))
+	return int(h.Sum32() % uint32(shards))
+}
@@ -0,0 +1,145 @@
+package dgraph_imei
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// fakeBatchWriter is a batch writer whose upserts and checkpoints are
+// recorded instead of written to Dgraph. Batches holding a call made from
+// the MSDIN fail are not recorded and return an error.
+type fakeBatchWriter struct {
+	*batchWriter
+	mu          sync.Mutex
+	batches     [][]*Call
+	checkpoints []int
+	fail        string
+}
+
+func newFakeBatchWriter(size, workers int) *fakeBatchWriter {
+	f := &fakeBatchWriter{batchWriter: newBatchWriter(nil, size, workers, retryPolicy{}, nil)}
+	f.upsert = func(_ context.Context, calls []*Call) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, call := range calls {
+			if call.Msdin == f.fail {
+				return fmt.Errorf("upsert of %s failed", call.Msdin)
+			}
+		}
+		f.batches = append(f.batches, append([]*Call(nil), calls...))
+		return nil
+	}
+	f.store = func(_ context.Context, cp *importCheckpoint) error {
+		f.checkpoints = append(f.checkpoints, cp.position)
+		return nil
+	}
+	return f
+}
+
+// shardMSDINs returns an MSDIN for each of the given number of shards.
+func shardMSDINs(t *testing.T, shards int) []string {
+	t.Helper()
+	msdins := make([]string, shards)
+	found := 0
+	for n := 79160000000; found < shards && n < 79160001000; n++ {
+		msdin := fmt.Sprintf("+%d", n)
+		if i := shardOf(msdin, shards); msdins[i] == "" {
+			msdins[i] = msdin
+			found++
+		}
+	}
+	if found < shards {
+		t.Fatalf("found MSDINs for %d of %d shards", found, shards)
+	}
+	return msdins
+}
+
+func TestBatchWriterRounds(t *testing.T) {
+	ctx := context.Background()
+	msdins := shardMSDINs(t, 2)
+	w := newFakeBatchWriter(2, 2)
+
+	// The round is written once the batch of the first shard is full, and
+	// takes the pending call of the second shard with it.
+	for _, msdin := range []string{msdins[0], msdins[1]} {
+		if err := w.add(ctx, &Call{Msdin: msdin}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(w.batches) != 0 {
+		t.Fatalf("%d batches written before one was full", len(w.batches))
+	}
+	if err := w.add(ctx, &Call{Msdin: msdins[0]}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.batches) != 2 {
+		t.Fatalf("round wrote %d batches, want 2", len(w.batches))
+	}
+	for _, batch := range w.batches {
+		for _, call := range batch {
+			if call.Msdin != batch[0].Msdin {
+				t.Errorf("batch mixes accounts %s and %s", batch[0].Msdin, call.Msdin)
+			}
+		}
+	}
+
+	// The next flush writes only the calls added since.
+	if err := w.add(ctx, &Call{Msdin: msdins[1]}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.batches) != 3 || len(w.batches[2]) != 1 || w.batches[2][0].Msdin != msdins[1] {
+		t.Errorf("flush wrote %d batches, last %v", len(w.batches), w.batches[len(w.batches)-1])
+	}
+	if len(w.checkpoints) != 0 {
+		t.Errorf("stored checkpoints %v without any", w.checkpoints)
+	}
+}
+
+func TestBatchWriterCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	w := newFakeBatchWriter(2, 1)
+	for pos := 1; pos <= 5; pos++ {
+		if err := w.add(ctx, &Call{Msdin: "+79161234567"}, &importCheckpoint{position: pos}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A flush with nothing new stores nothing either.
+	if err := w.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 4, 5}; fmt.Sprint(w.checkpoints) != fmt.Sprint(want) {
+		t.Errorf("stored checkpoints %v, want %v", w.checkpoints, want)
+	}
+}
+
+func TestBatchWriterFailedShard(t *testing.T) {
+	ctx := context.Background()
+	msdins := shardMSDINs(t, 2)
+	w := newFakeBatchWriter(2, 2)
+	w.fail = msdins[1]
+
+	for pos, msdin := range []string{msdins[0], msdins[1]} {
+		if err := w.add(ctx, &Call{Msdin: msdin}, &importCheckpoint{position: pos + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.add(ctx, &Call{Msdin: msdins[1]}, &importCheckpoint{position: 3}); err == nil {
+		t.Fatal("the round of a failed batch succeeded")
+	}
+	// The other worker commits its batch, but the checkpoint stays before
+	// the round.
+	if len(w.batches) != 1 || w.batches[0][0].Msdin != msdins[0] {
+		t.Errorf("committed batches %v, want the batch of %s", w.batches, msdins[0])
+	}
+	if len(w.checkpoints) != 0 {
+		t.Errorf("stored checkpoints %v after the failed round", w.checkpoints)
+	}
+}
@@ -0,0 +1,57 @@
+package dgraph_imei
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultCallTimeLayouts are tried in order for call_time values that are
+// stored as text. Layouts without a zone offset are read in the source
+// time zone.
+var defaultCallTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"2006-01-02",
+}
+
+// maxExcelSerial is 9999-12-31, the last date Excel can represent.
+const maxExcelSerial = 2958465
+
+// callTimeParser converts call_time cells to time.Time.
+type callTimeParser struct {
+	layouts  []string
+	location *time.Location
+	// date1904 is set for workbooks that count serial dates from 1904.
+	date1904 bool
+}
+
+// parse reads value with the configured layouts. Numbers that match no
+// layout are Excel serial dates, which is how spreadsheets store date
+// cells. The result is in UTC.
+func (p *callTimeParser) parse(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, rejectf(ReasonEmptyValue, "the string is empty")
+	}
+	for _, layout := range p.layouts {
+		if t, err := time.ParseInLocation(layout, value, p.location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 && serial <= maxExcelSerial {
+		t, err := excelize.ExcelDateToTime(serial, p.date1904)
+		if err == nil {
+			// Serial dates carry the wall clock of the source, not an
+			// instant, and are only precise to about a millisecond.
+			t = t.Round(time.Millisecond)
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.location).UTC(), nil
+		}
+	}
+	return time.Time{}, rejectf(ReasonInvalidDatetime, "unsupported datetime format: %q", value)
+}
@@ -0,0 +1,48 @@
+package dgraph_imei
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallTimeParse(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	utc := &callTimeParser{layouts: defaultCallTimeLayouts, location: time.UTC}
+	msk := &callTimeParser{layouts: defaultCallTimeLayouts, location: moscow}
+	ddmm := &callTimeParser{layouts: []string{"02/01/06 15:04"}, location: moscow}
+	mac := &callTimeParser{layouts: defaultCallTimeLayouts, location: time.UTC, date1904: true}
+
+	tests := []struct {
+		p     *callTimeParser
+		value string
+		want  string
+	}{
+		{utc, "2024-03-16T00:04:05", "2024-03-16T00:04:05Z"},
+		{msk, "2024-03-16T03:04:05", "2024-03-16T00:04:05Z"},
+		{msk, "2024-03-16T03:04:05+01:00", "2024-03-16T02:04:05Z"},
+		{msk, "16.03.2024 03:04", "2024-03-16T00:04:00Z"},
+		{ddmm, "16/03/24 03:04", "2024-03-16T00:04:00Z"},
+		{utc, "45367.0028356482", "2024-03-16T00:04:05Z"},
+		{msk, "45367.0028356482", "2024-03-15T21:04:05Z"},
+		{mac, "43905.0028356482", "2024-03-16T00:04:05Z"},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.parse(tt.value)
+		if err != nil {
+			t.Errorf("parse(%q) error: %v", tt.value, err)
+			continue
+		}
+		if s := got.Format(time.RFC3339Nano); s != tt.want {
+			t.Errorf("parse(%q) = %s, want %s", tt.value, s, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "-1", "16/03/24", "3000000"} {
+		if _, err := utc.parse(value); err == nil {
+			t.Errorf("parse(%q) succeeded, want error", value)
+		}
+	}
+}
@@ -1,62 +1,308 @@
 package dgraph_imei
 
 import (
-	"log"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/dgraph-io/dgo/v230"
 	"github.com/dgraph-io/dgo/v230/protos/api"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type FileClient struct {
-	dgraphClient   *dgo.Dgraph
-	grpcServerAddr string
+	dgraphConn   *grpc.ClientConn
+	dgraphClient *dgo.Dgraph
+	xlsxConn     *grpc.ClientConn
+	xlsxClient   XlsxServiceClient
+	batchSize    int
+	workers      int
+	retry        retryPolicy
+	mapping      *MappingProfile
+	msisdn       MSISDNNormalizer
+	dgraphTLS    *TLSFiles
+	xlsxTLS      *TLSFiles
+	xlsxCreds    credentials.PerRPCCredentials
+	login        *dgraphLogin
+	namespace    uint64
+	session      *dgraphSession
+}
+
+// dgraphLogin holds the credentials of a Dgraph ACL user.
+type dgraphLogin struct {
+	user, password string
 }
 
 type Call struct {
-	Msdin	   string  `json:"MSDIN"`
-	ImeiFrom   string  `json:"IMEI_FROM"`
-	Latitude   float64 `json:"latitude"`
-	Longitude  float64 `json:"longitude"`
-	Duration   float64 `json:"duration"`
-	ImeiTo	   string  `json:"IMEI_TO"`
-	CallTime   string  `json:"call_time"`
-	DgraphType string  `json:"dgraph.type"`
-}
-
-func NewClient(dgraphGRPCAddr, grpcServerAddr string) *FileClient {
-	dc := newDgraphClient(dgraphGRPCAddr)
+	Msdin         string  `json:"MSDIN"`
+	MsdinOriginal string  `json:"MSDIN_original,omitempty"`
+	ImeiFrom      string  `json:"IMEI_FROM"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Duration      float64 `json:"duration"`
+	ImeiTo        string  `json:"IMEI_TO"`
+	CallTime      string  `json:"call_time"`
+	DgraphType    string  `json:"dgraph.type"`
+	// HasLatitude, HasLongitude and HasDuration are set when the optional
+	// fields were given. Missing ones are not stored rather than stored as 0.
+	HasLatitude  bool `json:"-"`
+	HasLongitude bool `json:"-"`
+	HasDuration  bool `json:"-"`
+}
+
+// Key identifies a call independently of the file it was read from. It is a
+// hex SHA-256 hash of the MSDIN, both IMEIs, the call time and the duration,
+// so the same call found in several imports is stored only once.
+func (c *Call) Key() string {
+	callTime := c.CallTime
+	if t, err := time.Parse(time.RFC3339Nano, callTime); err == nil {
+		callTime = t.UTC().Format(time.RFC3339Nano)
+	}
+	h := sha256.New()
+	for _, field := range []string{c.Msdin, c.ImeiFrom, c.ImeiTo, callTime, strconv.FormatFloat(c.Duration, 'g', -1, 64)} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewClient connects to Dgraph and to the XlsxService server and makes sure
+// the graph schema is up to date. The returned client must be closed.
+func NewClient(dgraphGRPCAddr, grpcServerAddr string, opts ...Option) (*FileClient, error) {
 	client := &FileClient{
-		dgraphClient: dc,
-		grpcServerAddr: grpcServerAddr,
+		batchSize: defaultBatchSize,
+		workers:   defaultWorkers,
+		retry:     defaultRetryPolicy(),
+		mapping:   DefaultMappingProfile(),
+		msisdn:    DefaultMSISDNNormalizer,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if _, err := newRowParser(client.mapping, client.msisdn); err != nil {
+		return nil, fmt.Errorf("invalid mapping profile: %w", err)
+	}
+	if client.namespace != 0 && client.login == nil {
+		return nil, errors.New("a Dgraph namespace can only be used with a login")
+	}
+	dgraphCreds, err := transportCredentials(client.dgraphTLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Dgraph TLS configuration: %w", err)
+	}
+	xlsxCreds, err := transportCredentials(client.xlsxTLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XlsxService TLS configuration: %w", err)
+	}
+
+	if client.dgraphConn, client.dgraphClient, err = newDgraphClient(dgraphGRPCAddr, dgraphCreds); err != nil {
+		return nil, err
+	}
+	xlsxOpts := []grpc.DialOption{xlsxCreds}
+	if client.xlsxCreds != nil {
+		xlsxOpts = append(xlsxOpts, grpc.WithPerRPCCredentials(client.xlsxCreds))
 	}
-	return client
+	if client.xlsxConn, err = grpc.Dial(grpcServerAddr, xlsxOpts...); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("cannot dial XlsxService server: %w", err)
+	}
+	client.xlsxClient = NewXlsxServiceClient(client.xlsxConn)
+
+	if client.login != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+		client.session, err = startDgraphSession(ctx, client.dgraphClient, client.login.user, client.login.password, client.namespace)
+		cancel()
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("cannot log in to Dgraph namespace %d: %w", client.namespace, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), schemaTimeout)
+	err = ensureSchema(ctx, client.dgraphClient)
+	cancel()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("cannot bootstrap Dgraph schema: %w", err)
+	}
+	return client, nil
+}
+
+// Close releases the connections to Dgraph and to the XlsxService server.
+func (c *FileClient) Close() error {
+	var errs []error
+	if c.session != nil {
+		c.session.close()
+		c.session = nil
+	}
+	if c.dgraphConn != nil {
+		errs = append(errs, c.dgraphConn.Close())
+	}
+	if c.xlsxConn != nil {
+		errs = append(errs, c.xlsxConn.Close())
+	}
+	return errors.Join(errs...)
 }
 
 // ReadXLSXFile reads an xlsx file with a given name or path from GRPC server
-func (c *FileClient) ReadXLSXFile(filename string) error {
-	data, err := readXLSXFile(filename, c.grpcServerAddr)
+// and writes every valid call to Dgraph while the file is being parsed.
+// Cancelling ctx stops both the transfer and the Dgraph writes. The report
+// lists the rows that were rejected and is returned even on error, covering
+// the part of the file that was processed.
+//
+// The file is spooled to a temporary file, not kept in memory. Worksheets
+// are decoded one row at a time and the shared strings table, which holds
+// the text values of the workbook, is spooled too; memory only grows by an
+// 8-byte offset per distinct text value.
+func (c *FileClient) ReadXLSXFile(ctx context.Context, filename string) (*Report, error) {
+	return c.ingest(ctx, filename, FormatXLSX)
+}
+
+// ReadFile works like ReadXLSXFile for any supported format, chosen by the
+// file extension: .xlsx, .csv, .tsv, .jsonl or .parquet. The encoding and
+// delimiter of text files are detected automatically.
+func (c *FileClient) ReadFile(ctx context.Context, filename string) (*Report, error) {
+	return c.Ingest(ctx, IngestRequest{FileName: filename})
+}
+
+// IngestRequest names a file to ingest.
+type IngestRequest struct {
+	// FileName is the name or path of the file on the GRPC server.
+	FileName string
+	// Format overrides the format otherwise chosen by the file extension.
+	Format Format
+}
+
+// Ingest works like ReadFile, but the format can also be given explicitly,
+// for files whose extension does not tell it.
+func (c *FileClient) Ingest(ctx context.Context, req IngestRequest) (*Report, error) {
+	format := req.Format
+	if format == "" {
+		var err error
+		if format, err = formatFromName(req.FileName); err != nil {
+			return &Report{}, err
+		}
+	}
+	return c.ingest(ctx, req.FileName, format)
+}
+
+// UploadFile sends a local file to the GRPC server and returns the upload
+// ID the server stored it under. The ID keeps the file extension and can be
+// passed to ReadFile or Ingest as the file name.
+func (c *FileClient) UploadFile(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return err
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
 	}
 
-	for _, call := range data {
-		err = upsertAll(c.dgraphClient, call)
+	stream, err := c.xlsxClient.UploadXlsx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not start the upload: %w", err)
+	}
+	meta := &UploadMetadata{FileName: filepath.Base(path), Size: info.Size()}
+	if err := stream.Send(&UploadXlsxRequest{Data: &UploadXlsxRequest_Metadata{Metadata: meta}}); err != nil {
+		return "", uploadError(stream, err)
+	}
+	buffer := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buffer)
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return err
+			return "", err
 		}
+		if err := stream.Send(&UploadXlsxRequest{Data: &UploadXlsxRequest_Chunk{Chunk: buffer[:n]}}); err != nil {
+			return "", uploadError(stream, err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return "", fmt.Errorf("upload failed: %w", err)
+	}
+	return resp.GetUploadId(), nil
+}
+
+// uploadError returns the error the server ended the upload with. Send only
+// reports io.EOF when the server has already closed the stream.
+func uploadError(stream XlsxService_UploadXlsxClient, err error) error {
+	if err == io.EOF {
+		_, err = stream.CloseAndRecv()
+	}
+	return fmt.Errorf("upload failed: %w", err)
+}
+
+// ingest records the import in the ledger, resuming an earlier run of the
+// same file if it did not finish, and writes the calls of the file to
+// Dgraph.
+func (c *FileClient) ingest(ctx context.Context, filename string, format Format) (*Report, error) {
+	report := &Report{}
+	parser, err := newRowParser(c.mapping, c.msisdn)
+	if err != nil {
+		return report, err
+	}
+
+	spool, hash, err := fetchFile(ctx, c.xlsxClient, filename)
+	if err != nil {
+		return report, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	run, err := beginImport(ctx, c.dgraphClient, filename, format, hash)
+	if err != nil {
+		return report, err
+	}
+	report.ImportID, report.Skipped = run.uid, run.checkpoint
+
+	err = c.readImport(ctx, run, spool, filename, format, parser, report)
+	if ledgerErr := finishImport(ctx, c.dgraphClient, run, report, err); err == nil {
+		err = ledgerErr
+	}
+	return report, err
+}
+
+func (c *FileClient) readImport(ctx context.Context, run *importRun, spool *os.File, filename string, format Format, parser *rowParser, report *Report) error {
+	src, err := openSource(format, filename, spool, parser)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w := newBatchWriter(c.dgraphClient, c.batchSize, c.workers, c.retry, &run.node)
+	err = readRecords(ctx, src, parser, report, run.checkpoint, func(call *Call, pos int) error {
+		return w.add(ctx, call, &importCheckpoint{
+			node:     run.node,
+			position: pos,
+			accepted: run.accepted + report.Accepted + 1,
+			rejected: run.rejected + len(report.Rejected),
+		})
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return w.flush(ctx)
 }
 
-func newDgraphClient(dgraphGRPCAddr string) *dgo.Dgraph {
-	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
-	conn, err := grpc.Dial(dgraphGRPCAddr, dialOpts...)
+func newDgraphClient(dgraphGRPCAddr string, creds grpc.DialOption) (*grpc.ClientConn, *dgo.Dgraph, error) {
+	conn, err := grpc.Dial(dgraphGRPCAddr, creds)
 	if err != nil {
-		log.Fatalf("Cannot dial Dgraph client: %v", err)
+		return nil, nil, fmt.Errorf("cannot dial Dgraph client: %w", err)
 	}
 
-	return dgo.NewDgraphClient(
+	return conn, dgo.NewDgraphClient(
 		api.NewDgraphClient(conn),
-	)
+	), nil
 }
@@ -1,14 +1,28 @@
 package dgraph_imei
 
 import (
-	"log"
+	"context"
 	"testing"
 )
 
 func TestClient(t *testing.T) {
 	go runTestServer() // running test server
-	cli := NewClient("localhost:9080", ":50051")
-	if err := cli.ReadXLSXFile("test_file.xlsx"); err != nil {
-		log.Fatalf("Failed to parse xlsx file: %v", err)
+	cli, err := NewClient("localhost:9080", ":50051")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer cli.Close()
+	if _, err := cli.ReadXLSXFile(context.Background(), "test_file.xlsx"); err != nil {
+		t.Fatalf("Failed to parse xlsx file: %v", err)
+	}
+}
+
+func TestNewClientMappingProfile(t *testing.T) {
+	// The profile is checked before connecting, so no Dgraph is needed.
+	for _, p := range []*MappingProfile{nil, {Sheet: "Calls", SheetIndex: 2}} {
+		if cli, err := NewClient("localhost:9080", ":50051", WithMappingProfile(p)); err == nil {
+			cli.Close()
+			t.Errorf("NewClient accepted mapping profile %+v", p)
+		}
 	}
 }
@@ -0,0 +1,168 @@
+// Command xlsx-server serves the files of a directory over the XlsxService
+// gRPC API, along with the standard gRPC health checking service. It shuts
+// down gracefully on SIGTERM or SIGINT.
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	imei "github.com/zgordan-vv/dgraph_imei"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	addr := flag.String("addr", ":50051", "the address to listen on")
+	root := flag.String("root", ".", "the directory whose files are served")
+	uploadDir := flag.String("upload-dir", "", "the directory to store uploads in; uploads are refused if empty")
+	maxUploadSize := flag.Int64("max-upload-size", 1<<30, "the maximum size of an uploaded file in bytes, 0 for no limit")
+	extensions := flag.String("extensions", "", "comma separated file extensions to serve, like .xlsx,.csv; all supported formats if empty")
+	maxStreams := flag.Uint("max-streams", 0, "the maximum number of concurrent requests per connection, 0 for the gRPC default")
+	requestTimeout := flag.Duration("request-timeout", 10*time.Minute, "the time after which a request is cancelled, 0 for no limit")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "how long to wait for running requests on shutdown")
+	tlsCert := flag.String("tls-cert", "", "the PEM certificate to serve TLS with")
+	tlsKey := flag.String("tls-key", "", "the PEM key of the TLS certificate")
+	clientCA := flag.String("tls-client-ca", "", "the PEM CA certificates of the clients; requires client certificates (mutual TLS) if set")
+	tokensFile := flag.String("tokens", "", "a YAML file mapping static bearer tokens to identities")
+	jwtKeyFile := flag.String("jwt-key", "", "the PEM public key verifying JWT bearer tokens, whose subject is the identity")
+	policyFile := flag.String("policy", "", "a YAML file mapping identities to the directories they may read; all directories if empty")
+	mappingFile := flag.String("mapping", "", "a JSON or YAML mapping profile for ParseCalls; the default profile if empty")
+	msisdn := imei.DefaultMSISDNNormalizer
+	flag.StringVar(&msisdn.CountryCode, "country-code", msisdn.CountryCode, "the calling code ParseCalls assumes for MSDINs without one")
+	flag.StringVar(&msisdn.TrunkPrefix, "trunk-prefix", msisdn.TrunkPrefix, "the prefix of MSDINs in national format")
+	flag.StringVar(&msisdn.InternationalPrefix, "international-prefix", msisdn.InternationalPrefix, "the prefix dialled before a country code instead of +")
+	flag.IntVar(&msisdn.NationalLength, "national-length", msisdn.NationalLength, "the length of national significant numbers, 0 if it varies")
+	flag.Parse()
+
+	opts := []imei.ServerOption{
+		imei.WithUploadDir(*uploadDir),
+		imei.WithMaxUploadSize(*maxUploadSize),
+		imei.WithMaxConcurrentStreams(uint32(*maxStreams)),
+		imei.WithRequestTimeout(*requestTimeout),
+		imei.WithShutdownTimeout(*shutdownTimeout),
+		imei.WithServerMSISDNNormalizer(msisdn),
+	}
+	if *tlsCert != "" || *tlsKey != "" || *clientCA != "" {
+		opts = append(opts, imei.WithTLS(imei.TLSFiles{CAFile: *clientCA, CertFile: *tlsCert, KeyFile: *tlsKey}))
+	}
+	if *tokensFile != "" || *jwtKeyFile != "" {
+		auth, policy, err := loadAuth(*tokensFile, *jwtKeyFile, *policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load the authentication: %v", err)
+		}
+		opts = append(opts, imei.WithAuth(auth, policy))
+	}
+	if *mappingFile != "" {
+		mapping, err := imei.LoadMappingProfile(*mappingFile)
+		if err != nil {
+			log.Fatalf("Failed to load the mapping profile: %v", err)
+		}
+		opts = append(opts, imei.WithServerMappingProfile(mapping))
+	}
+	if *extensions != "" {
+		opts = append(opts, imei.WithExtensions(strings.Split(*extensions, ",")...))
+	}
+	srv, err := imei.NewXlsxServer(*root, opts...)
+	if err != nil {
+		log.Fatalf("Failed to create the server: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	log.Printf("Serving %s on %s", *root, *addr)
+	if err := srv.ListenAndServe(ctx, *addr); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+	log.Printf("Server stopped")
+}
+
+// loadAuth returns an authenticator accepting the static tokens of
+// tokensFile and the JWTs signed by the key of jwtKeyFile, and the policy of
+// policyFile. Empty file names are skipped.
+func loadAuth(tokensFile, jwtKeyFile, policyFile string) (imei.Authenticator, imei.AccessPolicy, error) {
+	var auths []imei.Authenticator
+	if tokensFile != "" {
+		var tokens map[string]string
+		if err := loadYAML(tokensFile, &tokens); err != nil {
+			return nil, nil, err
+		}
+		auths = append(auths, imei.StaticTokens(tokens))
+	}
+	if jwtKeyFile != "" {
+		key, methods, err := loadPublicKey(jwtKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		auths = append(auths, imei.JWTAuthenticator(key, methods...))
+	}
+	var policy imei.AccessPolicy
+	if policyFile != "" {
+		if err := loadYAML(policyFile, &policy); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	auth := func(ctx context.Context, token string) (string, error) {
+		var errs []error
+		for _, a := range auths {
+			identity, err := a(ctx, token)
+			if err == nil {
+				return identity, nil
+			}
+			errs = append(errs, err)
+		}
+		return "", errors.Join(errs...)
+	}
+	return auth, policy, nil
+}
+
+func loadYAML(name string, v any) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// loadPublicKey reads a PEM public key and returns it with the JWT signing
+// algorithms it verifies.
+func loadPublicKey(name string) (any, []string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s holds no PEM block", name)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return key, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	case *ecdsa.PublicKey:
+		return key, []string{"ES256", "ES384", "ES512"}, nil
+	case ed25519.PublicKey:
+		return key, []string{"EdDSA"}, nil
+	default:
+		return nil, nil, fmt.Errorf("%s: unsupported key type %T", name, key)
+	}
+}
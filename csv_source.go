@@ -0,0 +1,135 @@
+package dgraph_imei
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffSize is how much of a file is examined to detect its encoding and
+// delimiter.
+const sniffSize = 64 * 1024
+
+// csvDelimiters are the candidates tried when detecting the delimiter.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// csvSource reads delimited text files. The encoding is detected from the
+// byte order mark or the content, and the delimiter from the first line.
+type csvSource struct {
+	name string
+	r    *csv.Reader
+}
+
+func newCSVSource(r io.Reader, name string, tab bool) (*csvSource, error) {
+	raw := bufio.NewReaderSize(r, sniffSize)
+	sample, err := raw.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	text := bufio.NewReaderSize(transform.NewReader(raw, detectEncoding(sample).NewDecoder()), sniffSize)
+
+	delimiter := '\t'
+	if !tab {
+		line, err := text.Peek(sniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		delimiter = detectDelimiter(line)
+	}
+
+	cr := csv.NewReader(text)
+	cr.Comma = delimiter
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	return &csvSource{name: name, r: cr}, nil
+}
+
+func (s *csvSource) next() (record, error) {
+	cells, err := s.r.Read()
+	if err != nil {
+		return record{}, err
+	}
+	// Rows are numbered by line, as spreadsheet applications show them.
+	line, _ := s.r.FieldPos(0)
+	return record{sheet: s.name, row: line, cells: cells}, nil
+}
+
+func (s *csvSource) Close() error {
+	return nil
+}
+
+// detectEncoding recognises UTF-8 and UTF-16 by their byte order marks or,
+// without one, UTF-16 by its zero bytes and UTF-8 by being valid. Anything
+// else is taken as Windows-1251, the usual encoding of legacy exports.
+func detectEncoding(sample []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	}
+
+	var even, odd int
+	for i, b := range sample {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	switch {
+	case odd > len(sample)/4 && odd > even:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case even > len(sample)/4 && even > odd:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+
+	// The sample may end in the middle of a multi-byte character.
+	for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	if utf8.Valid(sample) {
+		return unicode.UTF8
+	}
+	return charmap.Windows1251
+}
+
+// detectDelimiter picks the candidate delimiter that occurs most often
+// outside of quotes in the first line of text.
+func detectDelimiter(text []byte) rune {
+	counts := make(map[rune]int)
+	quoted := false
+	for _, r := range string(text) {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+		if quoted {
+			continue
+		}
+		if r == '\n' {
+			break
+		}
+		counts[r]++
+	}
+
+	best := csvDelimiters[0]
+	for _, d := range csvDelimiters {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
@@ -0,0 +1,88 @@
+package dgraph_imei
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const csvCalls = "Номер;IMEI_FROM;latitude;longitude;duration;IMEI_TO;call_time\r\n" +
+	"89161234567;490154203237518;55,7558;37,6173;12;356938035643809;16.03.2024 10:04:05\r\n" +
+	"\"+7 (916) 123-45-68\";\"352099001761481\";\"55,7558\";\"37,6173\";\"24\";\"490154203237518\";\"2024-03-16T10:04:05+03:00\"\r\n" +
+	"\r\n" +
+	"BAD;490154203237518;55,7558;37,6173;1;356938035643809;16.03.2024 11:04:05\r\n"
+
+func TestCSVSource(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	utf16beNoBOM := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+	}{
+		{"utf-8", encoding.Nop},
+		{"utf-8 bom", unicode.UTF8BOM},
+		{"utf-16le bom", utf16le},
+		{"utf-16be", utf16beNoBOM},
+		{"windows-1251", charmap.Windows1251},
+	}
+
+	mapping := &MappingProfile{
+		Columns:  map[string]ColumnSpec{"MSDIN": {Aliases: []string{"Номер"}}},
+		TimeZone: "Europe/Moscow",
+	}
+	for _, tt := range tests {
+		data, err := tt.enc.NewEncoder().Bytes([]byte(csvCalls))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		parser, err := newRowParser(mapping, DefaultMSISDNNormalizer)
+		if err != nil {
+			t.Skipf("time zone database unavailable: %v", err)
+		}
+		src, err := newCSVSource(bytes.NewReader(data), "calls.csv", false)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		var calls []*Call
+		report := &Report{}
+		err = readRecords(context.Background(), src, parser, report, 0, func(c *Call, _ int) error {
+			calls = append(calls, c)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(calls) != 2 || report.Accepted != 2 {
+			t.Fatalf("%s: got %d calls, want 2", tt.name, len(calls))
+		}
+		if c := calls[0]; c.Msdin != "+79161234567" || c.Latitude != 55.7558 || c.CallTime != "2024-03-16T07:04:05Z" {
+			t.Errorf("%s: first call = %+v", tt.name, *c)
+		}
+		if c := calls[1]; c.Msdin != "+79161234568" || c.ImeiFrom != "352099001761481" || c.CallTime != "2024-03-16T07:04:05Z" {
+			t.Errorf("%s: second call = %+v", tt.name, *c)
+		}
+		if len(report.Rejected) != 1 || report.Rejected[0].Row != 5 || report.Rejected[0].Column != "Номер" {
+			t.Errorf("%s: rejected = %+v", tt.name, report.Rejected)
+		}
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := map[string]rune{
+		"a,b,c\n1;2":         ',',
+		"a;b;c\n":            ';',
+		"a\tb\tc":            '\t',
+		`"a;b","c;d"|e|f`:    '|',
+		"single column\n1,2": ',',
+	}
+	for line, want := range tests {
+		if got := detectDelimiter([]byte(line)); got != want {
+			t.Errorf("detectDelimiter(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
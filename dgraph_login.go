@@ -0,0 +1,102 @@
+package dgraph_imei
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// refreshMargin is how long before the access JWT expires it is
+	// refreshed.
+	refreshMargin = 30 * time.Second
+	// minRefreshDelay keeps a failing refresh from being retried in a tight
+	// loop.
+	minRefreshDelay = 5 * time.Second
+	loginTimeout    = 10 * time.Second
+)
+
+// dgraphSession keeps a client logged in to Dgraph. It refreshes the access
+// JWT before it expires, and logs in again with the password when the
+// refresh JWT has expired too, so long imports do not fail on an expired
+// token.
+type dgraphSession struct {
+	client    *dgo.Dgraph
+	user      string
+	password  string
+	namespace uint64
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// startDgraphSession logs in to the namespace and starts refreshing the
+// login in the background until close is called.
+func startDgraphSession(ctx context.Context, client *dgo.Dgraph, user, password string, namespace uint64) (*dgraphSession, error) {
+	s := &dgraphSession{
+		client:    client,
+		user:      user,
+		password:  password,
+		namespace: namespace,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err := client.LoginIntoNamespace(ctx, user, password, namespace); err != nil {
+		return nil, err
+	}
+	go s.refresh()
+	return s, nil
+}
+
+func (s *dgraphSession) refresh() {
+	defer close(s.done)
+	for {
+		delay, ok := refreshDelay(s.client.GetJwt().AccessJwt, time.Now())
+		if !ok {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(delay):
+		}
+		s.renew()
+	}
+}
+
+// renew refreshes the access JWT with the refresh JWT or, if that fails,
+// logs in again. A failed renewal is retried after minRefreshDelay; dgo
+// also refreshes an expired token itself when a request fails on it.
+func (s *dgraphSession) renew() {
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+	err := s.client.Relogin(ctx)
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to refresh the Dgraph login, logging in again: %v", err)
+	if err := s.client.LoginIntoNamespace(ctx, s.user, s.password, s.namespace); err != nil {
+		log.Printf("Failed to log in to Dgraph: %v", err)
+	}
+}
+
+func (s *dgraphSession) close() {
+	close(s.stop)
+	<-s.done
+}
+
+// refreshDelay returns how long after now the access JWT should be
+// refreshed. It is false if the token does not expire. The token is not
+// verified: it only tells when Dgraph will stop accepting it.
+func refreshDelay(accessJWT string, now time.Time) (time.Duration, bool) {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessJWT, claims); err != nil {
+		return minRefreshDelay, true
+	}
+	if claims.ExpiresAt == nil {
+		return 0, false
+	}
+	return max(claims.ExpiresAt.Sub(now)-refreshMargin, minRefreshDelay), true
+}
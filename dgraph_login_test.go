@@ -0,0 +1,43 @@
+package dgraph_imei
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRefreshDelay(t *testing.T) {
+	now := time.Date(2024, 3, 16, 10, 0, 0, 0, time.UTC)
+	token := func(claims jwt.RegisteredClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("dgraph"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tests := []struct {
+		name  string
+		jwt   string
+		delay time.Duration
+		ok    bool
+	}{
+		{"expiring", token(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute))}), 5*time.Minute - refreshMargin, true},
+		{"nearly expired", token(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(time.Second))}), minRefreshDelay, true},
+		{"expired", token(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(-time.Minute))}), minRefreshDelay, true},
+		{"not expiring", token(jwt.RegisteredClaims{Subject: "groot"}), 0, false},
+		{"malformed", "not a token", minRefreshDelay, true},
+	}
+	for _, tt := range tests {
+		delay, ok := refreshDelay(tt.jwt, now)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("%s: refreshDelay = %s, %v, want %s, %v", tt.name, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
+func TestNamespaceRequiresLogin(t *testing.T) {
+	if _, err := NewClient("localhost:9080", "localhost:50051", WithNamespace(2)); err == nil {
+		t.Error("created a client for a namespace without a login")
+	}
+}
@@ -2,269 +2,143 @@ package dgraph_imei
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/dgraph-io/dgo/v230"
 	"github.com/dgraph-io/dgo/v230/protos/api"
 )
 
-const callSchema = `
-	call_time: datetime @index(day) .
-	latitude: float .
-	longitude: float .
-	duration: float .
-	IMEI_FROM_UID: uid .
-	IMEI_TO_UID: uid .
-	MSDIN_UID: uid .
-`
-
-const deviceSchema = `
-	IMEI: string @index(exact) .
-	imeis_to: [uid] .
-	incoming_msdin: [uid] .
-	outgoing_msdin: [uid] .
-`
-
-const accountSchema = `
-	MSDIN: string @index(exact) .
-	imeis: [uid] .
-	imeis_to: [uid] .
-`
-
-func alterSchema(client *dgo.Dgraph, schema string) error {
-	ctx := context.Background()
-	op := &api.Operation{Schema: schema}
-	if err := client.Alter(ctx, op); err != nil {
-		return fmt.Errorf("failed to alter schema: %w", err)
-	}
-	return nil
-}
-
-func upsertAll(client *dgo.Dgraph, call *Call) error {
-	ctx := context.Background()
-	if err := upsertDevice(ctx, client, call); err != nil {
-		return err
-	}
-	if err := upsertAccount(ctx, client, call); err != nil {
-		return err
-	}
-	if err := insertCall(ctx, client, call); err != nil {
-		return err
-	}
-	return nil
-}
-
-func upsertDevice(ctx context.Context, client *dgo.Dgraph, call *Call) error {
-	if err := alterSchema(client, deviceSchema); err != nil {
-		return err
+// upsertCalls writes a batch of calls in a single upsert request, linking
+// them to the import node imp unless it is nil. Transactions aborted by a
+// conflict are retried according to retry.
+func upsertCalls(ctx context.Context, client *dgo.Dgraph, calls []*Call, imp *nquadNode, retry retryPolicy) error {
+	if len(calls) == 0 {
+		return nil
 	}
 
-	txn := client.NewTxn()
-	defer txn.Discard(ctx)
-
-	upsertQuery := fmt.Sprintf(`
-		query {
-			q(func: eq(IMEI, "%s")) {
-				v as uid
-			}
-			q2(func: eq(IMEI, "%s")) {
-				v2 as uid
-			}
-		}`, call.ImeiFrom, call.ImeiTo)
-
-	mu := &api.Mutation{
-		SetNquads: []byte(fmt.Sprintf(`
-			uid(v) <IMEI> "%s" .
-			uid(v) <dgraph.type> "device" .
-			uid(v2) <IMEI> "%s" .
-			uid(v2) <dgraph.type> "device" .
-			uid(v) <imeis_to> uid(v2) .
-			uid(v2) <imeis_to> uid(v) .
-			`, call.ImeiFrom, call.ImeiTo)),
-	}
-
-	if _, err := txn.Do(ctx, &api.Request{Query: upsertQuery, Mutations: []*api.Mutation{mu}, CommitNow: true}); err != nil {
-		log.Printf("Failed to upsert devices: %v", err)
+	err := retry.do(ctx, func() error {
+		txn := client.NewTxn()
+		defer txn.Discard(ctx)
+		_, err := txn.Do(ctx, buildUpsertRequest(calls, imp))
 		return err
-	}
-	return nil
-}
-
-func updateDevicesWithAccount(ctx context.Context, client *dgo.Dgraph, imeiFromUid, imeiToUid, msdin string) error {
-	if err := alterSchema(client, deviceSchema); err != nil {
-		return err
-	}
-
-	txn0 := client.NewTxn()
-	defer txn0.Discard(ctx)
-
-	accountUid, err := accountUidByMsdin(ctx, txn0, msdin)
+	})
 	if err != nil {
+		log.Printf("Failed to upsert %d calls: %v", len(calls), err)
 		return err
 	}
-
-	ims := fmt.Sprintf(`<%s> <incoming_msdin> <%s> .`, imeiFromUid, accountUid)
-	imu := &api.Mutation{
-		SetNquads: []byte(ims),
-		CommitNow: true,
-	}
-
-	if _, err := txn0.Mutate(context.Background(), imu); err != nil {
-		return err
-	}
-
-	txn1 := client.NewTxn()
-	defer txn1.Discard(ctx)
-
-	oms := fmt.Sprintf(`<%s> <outgoing_msdin> <%s> .`, imeiToUid, accountUid)
-	omu := &api.Mutation{
-		SetNquads: []byte(oms),
-		CommitNow: true,
-	}
-
-	if _, err := txn1.Mutate(context.Background(), omu); err != nil {
-		return err
-	}
-	return err
+	return nil
 }
 
-func upsertAccount(ctx context.Context, client *dgo.Dgraph, call *Call) error {
-	if err := alterSchema(client, accountSchema); err != nil {
-		return err
-	}
-
-	txn := client.NewTxn()
-	defer txn.Discard(ctx)
-
-	imeiFromUid, err := deviceUidByImei(ctx, txn, call.ImeiFrom)
-	if err != nil {
-		return err
-	}
-	imeiToUid, err := deviceUidByImei(ctx, txn, call.ImeiTo)
-	if err != nil {
-		return err
-	}
-
-	upsertQuery := fmt.Sprintf(`query {
-		var(func: eq(MSDIN, "%s")) {
-			account as uid
+// buildUpsertRequest builds the upsert request for a batch of calls. Every
+// distinct IMEI and MSDIN of the batch is resolved by one variable of the
+// query block, so devices and accounts that are not in the graph yet are
+// created by the same mutation that links them to the calls. Calls are
+// resolved by their key the same way, so importing a call again updates
+// the existing node instead of adding a duplicate. Spreadsheet values only
+// ever reach Dgraph as query variables or escaped literals.
+//
+// When imp is not nil, the calls are linked to that import node.
+func buildUpsertRequest(calls []*Call, imp *nquadNode) *api.Request {
+	var params, blocks []string
+	vars := make(map[string]string)
+	nq := &nquadBuilder{}
+	devices := make(map[string]nquadNode)
+	accounts := make(map[string]nquadNode)
+	emitted := make(map[[3]string]bool)
+	keys := make(map[string]bool)
+
+	bind := func(prefix, predicate, value string) string {
+		name := fmt.Sprintf("%s%d", prefix, len(vars))
+		params = append(params, "$"+name+": string")
+		blocks = append(blocks, fmt.Sprintf("\t%s as var(func: eq(%s, $%s))", name, predicate, name))
+		vars["$"+name] = value
+		return name
+	}
+	device := func(imei string) nquadNode {
+		if n, ok := devices[imei]; ok {
+			return n
 		}
-	}`, call.Msdin)
-
-	mu := &api.Mutation{
-		SetNquads: []byte(fmt.Sprintf(`
-			uid(account) <MSDIN> "%s" .
-			uid(account) <dgraph.type> "account" .
-			uid(account) <imeis> <%s> .
-			uid(account) <imeis_to> <%s> .`, call.Msdin, imeiFromUid, imeiToUid)),
-	}
-
-	if _, err := txn.Do(ctx, &api.Request{Query: upsertQuery, Mutations: []*api.Mutation{mu}, CommitNow: true}); err != nil {
-		log.Printf("Failed to upsert account: %v", err)
-		return err
-	}
-	return updateDevicesWithAccount(ctx, client, imeiFromUid, imeiToUid, call.Msdin)
-}
-
-func insertCall(ctx context.Context, client *dgo.Dgraph, call *Call) error {
-	if err := alterSchema(client, callSchema); err != nil {
-		return err
-	}
-
-	txn := client.NewTxn()
-	defer txn.Discard(ctx)
-
-	imeiFromUid, err := deviceUidByImei(ctx, txn, call.ImeiFrom)
-	if err != nil {
-		return err
-	}
-
-	imeiToUid, err := deviceUidByImei(ctx, txn, call.ImeiTo)
-	if err != nil {
-		return err
-	}
-
-	msdinUid, err := accountUidByMsdin(ctx, txn, call.Msdin)
-	if err != nil {
-		return err
-	}
-
-        mutation := &api.Mutation{
-		SetNquads: []byte(fmt.Sprintf(`
-			_:call <call_time> "%s" .
-			_:call <latitude> "%f" .
-			_:call <longitude> "%f" .
-			_:call <duration> "%f" .
-			_:call <IMEI_FROM_UID> <%s> .
-			_:call <IMEI_TO_UID> <%s> .
-			_:call <MSDIN_UID> <%s> .
-			_:call <dgraph.type> "call" .
-		`,
-		call.CallTime, call.Latitude, call.Longitude, call.Duration, imeiFromUid, imeiToUid, msdinUid)),
-		CommitNow: true,
+		n := uidVar(bind("d", "IMEI", imei))
+		devices[imei] = n
+		nq.literal(n, "IMEI", imei)
+		if isValidIMEI(imei) {
+			nq.literal(n, "TAC", IMEI(imei).TAC())
+			nq.literal(n, "serial_number", IMEI(imei).SerialNumber())
+		}
+		nq.literal(n, "dgraph.type", "device")
+		return n
 	}
-	if _, err := txn.Mutate(ctx, mutation); err != nil {
-		log.Printf("Failed to insert call: %v", err)
-		return err
+	account := func(msdin string) nquadNode {
+		if n, ok := accounts[msdin]; ok {
+			return n
+		}
+		n := uidVar(bind("a", "MSDIN", msdin))
+		accounts[msdin] = n
+		nq.literal(n, "MSDIN", msdin)
+		nq.literal(n, "dgraph.type", "account")
+		return n
+	}
+	edge := func(from nquadNode, predicate string, to nquadNode) {
+		key := [3]string{from.s, predicate, to.s}
+		if !emitted[key] {
+			emitted[key] = true
+			nq.edge(from, predicate, to)
+		}
 	}
-	return nil
-}
 
-func deviceUidByImei(ctx context.Context, txn *dgo.Txn, imei string) (string, error) {
-	query := fmt.Sprintf(`{
-		Devices(func: eq(dgraph.type, "device")) @filter(eq(IMEI, %s)) {
-			uid
+	for _, call := range calls {
+		callKey := call.Key()
+		if keys[callKey] {
+			continue
 		}
-	}`, imei)
+		keys[callKey] = true
 
-	resp, err := txn.Query(context.Background(), query)
-	if err != nil {
-		return "", err
-	}
+		from := device(call.ImeiFrom)
+		to := device(call.ImeiTo)
+		acc := account(call.Msdin)
 
-	var result struct {
-		Devices []struct {
-			UID string `json:"uid"`
+		if call.MsdinOriginal != "" && call.MsdinOriginal != call.Msdin {
+			key := [3]string{acc.s, "MSDIN_original", call.MsdinOriginal}
+			if !emitted[key] {
+				emitted[key] = true
+				nq.literal(acc, "MSDIN_original", call.MsdinOriginal)
+			}
 		}
-	}
-	
-	if err := json.Unmarshal(resp.Json, &result); err != nil {
-		return "", err
-	}
-	
-	if len(result.Devices) > 0 {
-		return result.Devices[0].UID, nil
-	}
-	return "", errors.New("Device is not found")
-}
 
-func accountUidByMsdin(ctx context.Context, txn *dgo.Txn, msdin string) (string, error) {
-	query := fmt.Sprintf(`{
-		Accounts(func: eq(dgraph.type, "account")) @filter(eq(MSDIN, %s)) {
-			uid
+		edge(from, "imeis_to", to)
+		edge(to, "imeis_to", from)
+		edge(acc, "imeis", from)
+		edge(acc, "imeis_to", to)
+		edge(from, "incoming_msdin", acc)
+		edge(to, "outgoing_msdin", acc)
+
+		c := uidVar(bind("c", "call_key", callKey))
+		nq.literal(c, "call_key", callKey)
+		nq.typed(c, "call_time", call.CallTime, xsDateTime)
+		if call.HasLatitude {
+			nq.float(c, "latitude", call.Latitude)
 		}
-	}`, msdin)
-
-	resp, err := txn.Query(context.Background(), query)
-	if err != nil {
-		return "", err
-	}
-
-	var result struct {
-		Accounts []struct {
-			UID string `json:"uid"`
+		if call.HasLongitude {
+			nq.float(c, "longitude", call.Longitude)
+		}
+		if call.HasDuration {
+			nq.float(c, "duration", call.Duration)
+		}
+		nq.edge(c, "IMEI_FROM_UID", from)
+		nq.edge(c, "IMEI_TO_UID", to)
+		nq.edge(c, "MSDIN_UID", acc)
+		nq.literal(c, "dgraph.type", "call")
+		if imp != nil {
+			nq.edge(c, "imported_by", *imp)
 		}
 	}
-	
-	if err := json.Unmarshal(resp.Json, &result); err != nil {
-		return "", err
-	}
-	
-	if len(result.Accounts) > 0 {
-		return result.Accounts[0].UID, nil
+
+	return &api.Request{
+		Query:     "query q(" + strings.Join(params, ", ") + ") {\n" + strings.Join(blocks, "\n") + "\n}",
+		Vars:      vars,
+		Mutations: []*api.Mutation{{SetNquads: nq.bytes()}},
+		CommitNow: true,
 	}
-	return "", errors.New("Account is not found")
 }
@@ -0,0 +1,85 @@
+package dgraph_imei
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallKey(t *testing.T) {
+	call := Call{Msdin: "+79161234567", ImeiFrom: "490154203237518", ImeiTo: "356938035643809", CallTime: "2024-03-16T07:04:05Z", Duration: 12}
+
+	same := call
+	same.CallTime = "2024-03-16T10:04:05+03:00"
+	same.Latitude, same.MsdinOriginal = 55.7558, "89161234567"
+	if call.Key() != same.Key() {
+		t.Errorf("keys of the same call differ: %s, %s", call.Key(), same.Key())
+	}
+
+	other := call
+	other.Duration = 13
+	if call.Key() == other.Key() {
+		t.Errorf("calls of different duration have the same key %s", call.Key())
+	}
+}
+
+func TestBuildUpsertRequestDeduplicatesCalls(t *testing.T) {
+	call := &Call{Msdin: "+79161234567", ImeiFrom: "490154203237518", ImeiTo: "356938035643809", CallTime: "2024-03-16T07:04:05Z", Duration: 12}
+	again := *call
+	req := buildUpsertRequest([]*Call{call, &again}, nil)
+
+	if n := strings.Count(req.Query, "eq(call_key,"); n != 1 {
+		t.Fatalf("query resolves %d call keys, want 1:\n%s", n, req.Query)
+	}
+	var keys []string
+	for _, q := range parseNQuads(t, req.Mutations[0].SetNquads) {
+		if q.predicate == "call_key" {
+			keys = append(keys, q.object)
+		}
+	}
+	if len(keys) != 1 || keys[0] != call.Key() {
+		t.Errorf("mutation stores call keys %q, want %q", keys, call.Key())
+	}
+}
+
+func TestBuildUpsertRequestLinksImport(t *testing.T) {
+	imp, err := uidNode("0x2a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []*Call{
+		{Msdin: "+79161234567", ImeiFrom: "490154203237518", ImeiTo: "356938035643809", CallTime: "2024-03-16T07:04:05Z"},
+		{Msdin: "+79161234568", ImeiFrom: "490154203237518", ImeiTo: "356938035643809", CallTime: "2024-03-16T07:04:05Z"},
+	}
+	req := buildUpsertRequest(calls, &imp)
+
+	linked := make(map[string]bool)
+	for _, q := range parseNQuads(t, req.Mutations[0].SetNquads) {
+		if q.predicate == "imported_by" {
+			if q.object != "<0x2a>" {
+				t.Errorf("call %s is linked to %s", q.subject, q.object)
+			}
+			linked[q.subject] = true
+		}
+	}
+	if len(linked) != len(calls) {
+		t.Errorf("%d of %d calls are linked to the import", len(linked), len(calls))
+	}
+}
+
+func TestBuildUpsertRequestSkipsMissingFields(t *testing.T) {
+	call := &Call{Msdin: "+79161234567", ImeiFrom: "490154203237518", ImeiTo: "356938035643809", CallTime: "2024-03-16T07:04:05Z", Latitude: 55.7558, HasLatitude: true}
+	req := buildUpsertRequest([]*Call{call}, nil)
+
+	stored := make(map[string]string)
+	for _, q := range parseNQuads(t, req.Mutations[0].SetNquads) {
+		stored[q.predicate] = q.object
+	}
+	if _, ok := stored["latitude"]; !ok {
+		t.Error("latitude is not stored")
+	}
+	for _, predicate := range []string{"longitude", "duration"} {
+		if object, ok := stored[predicate]; ok {
+			t.Errorf("missing %s is stored as %s", predicate, object)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package dgraph_imei
+
+import "strings"
+
+// IMEI is a validated 15-digit International Mobile Equipment Identity:
+// an 8-digit Type Allocation Code, a 6-digit serial number and a Luhn
+// check digit.
+type IMEI string
+
+// ParseIMEI validates s as an IMEI. Spaces and dashes used as separators
+// are ignored. A 16-digit IMEISV is accepted and normalised to the IMEI of
+// the same device by replacing its software version with the check digit.
+func ParseIMEI(s string) (IMEI, error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) == 0 {
+		return "", rejectf(ReasonEmptyValue, "the string is empty")
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", rejectf(ReasonNotDigits, "IMEI contains a non-digit character %q", r)
+		}
+	}
+
+	switch len(digits) {
+	case 15:
+		if !isValidIMEI(digits) {
+			return "", rejectf(ReasonBadChecksum, "IMEI check digit does not match, expected %c", luhnCheckDigit(digits[:14]))
+		}
+		return IMEI(digits), nil
+	case 16: // IMEISV
+		body := digits[:14]
+		return IMEI(body + string(luhnCheckDigit(body))), nil
+	default:
+		return "", rejectf(ReasonInvalidIMEI, "IMEI must have 15 digits or 16 for an IMEISV, got %d", len(digits))
+	}
+}
+
+// TAC returns the Type Allocation Code identifying the device model.
+func (i IMEI) TAC() string {
+	return string(i[:8])
+}
+
+// SerialNumber returns the serial number assigned within the TAC.
+func (i IMEI) SerialNumber() string {
+	return string(i[8:14])
+}
+
+// isValidIMEI reports whether s is exactly 15 digits with a valid Luhn
+// check digit.
+func isValidIMEI(s string) bool {
+	if len(s) != 15 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(s[:14]) == s[14]
+}
+
+// luhnCheckDigit computes the Luhn check digit to append to body, which
+// must consist of digits only.
+func luhnCheckDigit(body string) byte {
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		d := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 { // every second digit from the right
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
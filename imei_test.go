@@ -0,0 +1,48 @@
+package dgraph_imei
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseIMEI(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   IMEI
+		reason RejectReason
+	}{
+		{in: "490154203237518", want: "490154203237518"},
+		{in: "35-209900-176148-1", want: "352099001761481"},
+		{in: "35 209900 176148 1", want: "352099001761481"},
+		{in: "3520990017614823", want: "352099001761481"}, // IMEISV
+		{in: "490154203237517", reason: ReasonBadChecksum},
+		{in: "49015420323751", reason: ReasonInvalidIMEI},
+		{in: "1111111", reason: ReasonInvalidIMEI},
+		{in: "49015420323751812", reason: ReasonInvalidIMEI},
+		{in: "49015420323751A", reason: ReasonNotDigits},
+		{in: "", reason: ReasonEmptyValue},
+	}
+	for _, tt := range tests {
+		got, err := ParseIMEI(tt.in)
+		if tt.reason != "" {
+			var re *reasonError
+			if !errors.As(err, &re) || re.reason != tt.reason {
+				t.Errorf("ParseIMEI(%q) error = %v, want reason %s", tt.in, err, tt.reason)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseIMEI(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestIMEIParts(t *testing.T) {
+	imei := IMEI("352099001761481")
+	if got := imei.TAC(); got != "35209900" {
+		t.Errorf("TAC() = %q, want 35209900", got)
+	}
+	if got := imei.SerialNumber(); got != "176148" {
+		t.Errorf("SerialNumber() = %q, want 176148", got)
+	}
+}
@@ -0,0 +1,178 @@
+package dgraph_imei
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+	"github.com/dgraph-io/dgo/v230/protos/api"
+)
+
+// Statuses of an import node. Only running and failed imports are resumed.
+const (
+	ImportRunning   = "running"
+	ImportFinished  = "finished"
+	ImportFailed    = "failed"
+	ImportReverting = "reverting"
+	ImportReverted  = "reverted"
+)
+
+// ledgerTimeout bounds the update of the import node after an import ends,
+// which is made even when the import was cancelled.
+const ledgerTimeout = 10 * time.Second
+
+// importRun is the import node of an ingestion in progress. An import that
+// did not finish is resumed when a file with the same content and format
+// is ingested again.
+type importRun struct {
+	uid  string
+	node nquadNode
+	// checkpoint, accepted and rejected describe what earlier runs of the
+	// import committed: the number of records of the file processed and
+	// the calls accepted and rows rejected among them.
+	checkpoint int
+	accepted   int
+	rejected   int
+}
+
+// importCheckpoint records how far an import got: every call up to the
+// record at position is committed. The counts include earlier runs.
+type importCheckpoint struct {
+	node     nquadNode
+	position int
+	accepted int
+	rejected int
+}
+
+// beginImport resumes the latest unfinished import of the file with the
+// given content hash and format, or records a new import.
+func beginImport(ctx context.Context, client *dgo.Dgraph, fileName string, format Format, hash string) (*importRun, error) {
+	txn := client.NewTxn()
+	defer txn.Discard(ctx)
+
+	resp, err := txn.QueryWithVars(ctx, `query q($hash: string) {
+		imports(func: eq(import_hash, $hash)) {
+			uid
+			import_format
+			import_status
+			import_started_at
+			import_checkpoint
+			import_accepted
+			import_rejected
+		}
+	}`, map[string]string{"$hash": hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up earlier imports: %w", err)
+	}
+	var result struct {
+		Imports []struct {
+			UID        string    `json:"uid"`
+			Format     Format    `json:"import_format"`
+			Status     string    `json:"import_status"`
+			StartedAt  time.Time `json:"import_started_at"`
+			Checkpoint int       `json:"import_checkpoint"`
+			Accepted   int       `json:"import_accepted"`
+			Rejected   int       `json:"import_rejected"`
+		} `json:"imports"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, err
+	}
+
+	run := &importRun{}
+	var startedAt time.Time
+	for _, imp := range result.Imports {
+		resumable := imp.Status == ImportRunning || imp.Status == ImportFailed
+		if imp.Format == format && resumable && !imp.StartedAt.Before(startedAt) {
+			run = &importRun{uid: imp.UID, checkpoint: imp.Checkpoint, accepted: imp.Accepted, rejected: imp.Rejected}
+			startedAt = imp.StartedAt
+		}
+	}
+
+	nq := &nquadBuilder{}
+	node := blankNode("import")
+	if run.uid != "" {
+		if node, err = uidNode(run.uid); err != nil {
+			return nil, err
+		}
+	} else {
+		nq.literal(node, "import_file", fileName)
+		nq.literal(node, "import_hash", hash)
+		nq.literal(node, "import_format", string(format))
+		nq.typed(node, "import_started_at", time.Now().UTC().Format(time.RFC3339Nano), xsDateTime)
+		nq.typed(node, "import_checkpoint", "0", xsInt)
+		nq.typed(node, "import_accepted", "0", xsInt)
+		nq.typed(node, "import_rejected", "0", xsInt)
+		nq.literal(node, "dgraph.type", "import")
+	}
+	nq.literal(node, "import_status", ImportRunning)
+
+	mu := &api.Mutation{SetNquads: nq.bytes(), CommitNow: true}
+	if run.uid != "" {
+		del := &nquadBuilder{}
+		del.wildcard(node, "import_error")
+		mu.DelNquads = del.bytes()
+	}
+	assigned, err := txn.Mutate(ctx, mu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the import: %w", err)
+	}
+	if run.uid == "" {
+		run.uid = assigned.Uids["import"]
+	}
+	if run.node, err = uidNode(run.uid); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// storeCheckpoint stores the checkpoint on its import node. It is written
+// after the calls it covers are committed; should the import be
+// interrupted in between, the resumed run writes some calls again, which
+// their keys make harmless.
+func storeCheckpoint(ctx context.Context, client *dgo.Dgraph, cp *importCheckpoint, retry retryPolicy) error {
+	nq := &nquadBuilder{}
+	nq.typed(cp.node, "import_checkpoint", strconv.Itoa(cp.position), xsInt)
+	nq.typed(cp.node, "import_accepted", strconv.Itoa(cp.accepted), xsInt)
+	nq.typed(cp.node, "import_rejected", strconv.Itoa(cp.rejected), xsInt)
+
+	err := retry.do(ctx, func() error {
+		txn := client.NewTxn()
+		defer txn.Discard(ctx)
+		_, err := txn.Mutate(ctx, &api.Mutation{SetNquads: nq.bytes(), CommitNow: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store the checkpoint of import %s: %w", cp.node.s, err)
+	}
+	return nil
+}
+
+// finishImport records the outcome of a run. The counts of a failed run
+// are left as the last committed batch stored them, matching its
+// checkpoint.
+func finishImport(ctx context.Context, client *dgo.Dgraph, run *importRun, report *Report, importErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ledgerTimeout)
+	defer cancel()
+
+	nq := &nquadBuilder{}
+	nq.typed(run.node, "import_finished_at", time.Now().UTC().Format(time.RFC3339Nano), xsDateTime)
+	if importErr != nil {
+		nq.literal(run.node, "import_status", ImportFailed)
+		nq.literal(run.node, "import_error", importErr.Error())
+	} else {
+		nq.literal(run.node, "import_status", ImportFinished)
+		nq.typed(run.node, "import_accepted", strconv.Itoa(run.accepted+report.Accepted), xsInt)
+		nq.typed(run.node, "import_rejected", strconv.Itoa(run.rejected+len(report.Rejected)), xsInt)
+	}
+
+	txn := client.NewTxn()
+	defer txn.Discard(ctx)
+	if _, err := txn.Mutate(ctx, &api.Mutation{SetNquads: nq.bytes(), CommitNow: true}); err != nil {
+		return fmt.Errorf("failed to record the end of import %s: %w", run.uid, err)
+	}
+	return nil
+}
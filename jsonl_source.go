@@ -0,0 +1,92 @@
+package dgraph_imei
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// jsonlSource reads JSON Lines files holding one call object per line,
+// keyed by the JSON names of the Call fields.
+type jsonlSource struct {
+	name string
+	r    *bufio.Reader
+	line int
+}
+
+func newJSONLSource(r io.Reader, name string) *jsonlSource {
+	return &jsonlSource{name: name, r: bufio.NewReaderSize(r, sniffSize)}
+}
+
+func (s *jsonlSource) next() (record, error) {
+	for {
+		data, err := s.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			return record{}, err
+		}
+		s.line++
+		if s.line == 1 {
+			data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+		}
+		if data = bytes.TrimSpace(data); len(data) == 0 {
+			continue
+		}
+		cells, rej := decodeCall(data)
+		return record{sheet: s.name, row: s.line, cells: cells, reject: rej}, nil
+	}
+}
+
+func (s *jsonlSource) Close() error {
+	return nil
+}
+
+func (s *jsonlSource) namedFields() {}
+
+// jsonCall is a Call whose numbers may be missing or null. They are
+// decoded as pointers so that an absent number yields an empty cell, which
+// the mapping profile rejects unless the field is optional, rather than 0.
+type jsonCall struct {
+	Call
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Duration  *float64 `json:"duration"`
+}
+
+// decodeCall decodes a JSON object into a Call and returns its fields in
+// the order of columnNames, so that they are validated and normalised the
+// same way as spreadsheet cells.
+func decodeCall(data []byte) ([]string, *Rejection) {
+	var call jsonCall
+	if err := json.Unmarshal(data, &call); err != nil {
+		var column string
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			column = typeErr.Field
+		}
+		return nil, newRejection(column, "", rejectf(ReasonMalformedRecord, "%v", err))
+	}
+	return callCells(&call), nil
+}
+
+// callCells returns the fields of c in the order of columnNames.
+func callCells(c *jsonCall) []string {
+	cells := make([]string, len(columnNames))
+	cells[colMsdin] = c.Msdin
+	cells[colImeiFrom] = c.ImeiFrom
+	cells[colLatitude] = formatOptionalFloat(c.Latitude)
+	cells[colLongitude] = formatOptionalFloat(c.Longitude)
+	cells[colDuration] = formatOptionalFloat(c.Duration)
+	cells[colImeiTo] = c.ImeiTo
+	cells[colCallTime] = c.CallTime
+	return cells
+}
+
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
@@ -0,0 +1,103 @@
+package dgraph_imei
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const jsonlCalls = `{"MSDIN":"89161234567","IMEI_FROM":"490154203237518","latitude":55.7558,"longitude":37.6173,"duration":12,"IMEI_TO":"356938035643809","call_time":"2024-03-16T10:04:05+03:00"}
+
+{"MSDIN":"+79161234568","IMEI_FROM":"352099001761481","IMEI_TO":"490154203237518","call_time":"2024-03-16 10:04:05","extra":true}
+{"MSDIN":"79161234569","IMEI_FROM":"490154203237518","duration":"12","IMEI_TO":"356938035643809","call_time":"2024-03-16T10:04:05Z"}
+{"MSDIN":"79161234569",
+{"MSDIN":"79161234569","IMEI_FROM":"490154203237518","latitude":55.7558,"longitude":37.6173,"duration":-1,"IMEI_TO":"356938035643809","call_time":"2024-03-16T10:04:05Z"}`
+
+func TestJSONLSource(t *testing.T) {
+	parser, err := newRowParser(DefaultMappingProfile(), DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, report := readTestRecords(t, newJSONLSource(strings.NewReader(jsonlCalls), "calls.jsonl"), parser)
+
+	if len(calls) != 1 || report.Accepted != 1 {
+		t.Fatalf("got %d calls, want 1", len(calls))
+	}
+	if c := calls[0]; c.Msdin != "+79161234567" || c.MsdinOriginal != "89161234567" || c.Latitude != 55.7558 || c.CallTime != "2024-03-16T07:04:05Z" {
+		t.Errorf("first call = %+v", *c)
+	}
+	want := []Rejection{
+		{Row: 3, Column: "latitude", Reason: ReasonEmptyValue},
+		{Row: 4, Column: "duration", Reason: ReasonMalformedRecord},
+		{Row: 5, Reason: ReasonMalformedRecord},
+		{Row: 6, Column: "duration", Value: "-1", Reason: ReasonNegativeNumber},
+	}
+	checkRejections(t, report, "calls.jsonl", want)
+}
+
+func TestJSONLSourceOptionalNumbers(t *testing.T) {
+	mapping := &MappingProfile{Columns: map[string]ColumnSpec{}}
+	for _, field := range []string{"latitude", "longitude", "duration"} {
+		mapping.Columns[field] = ColumnSpec{Optional: true}
+	}
+	parser, err := newRowParser(mapping, DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, report := readTestRecords(t, newJSONLSource(strings.NewReader(jsonlCalls), "calls.jsonl"), parser)
+
+	if len(calls) != 2 || report.Accepted != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if c := calls[1]; c.Msdin != "+79161234568" || c.Latitude != 0 || c.Duration != 0 || c.CallTime != "2024-03-16T10:04:05Z" {
+		t.Errorf("second call = %+v", *c)
+	}
+}
+
+func TestReadRecordsResume(t *testing.T) {
+	parser, err := newRowParser(DefaultMappingProfile(), DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The blank line is not a record, so the first two records are the
+	// two valid calls.
+	calls, report := resumeTestRecords(t, newJSONLSource(strings.NewReader(jsonlCalls), "calls.jsonl"), parser, 2)
+	if len(calls) != 0 || report.Accepted != 0 {
+		t.Fatalf("got %d calls, want none", len(calls))
+	}
+	if len(report.Rejected) != 3 || report.Rejected[0].Row != 4 {
+		t.Errorf("rejected = %+v", report.Rejected)
+	}
+}
+
+func readTestRecords(t *testing.T, src recordSource, parser *rowParser) ([]*Call, *Report) {
+	t.Helper()
+	return resumeTestRecords(t, src, parser, 0)
+}
+
+func resumeTestRecords(t *testing.T, src recordSource, parser *rowParser, skip int) ([]*Call, *Report) {
+	t.Helper()
+	var calls []*Call
+	report := &Report{}
+	err := readRecords(context.Background(), src, parser, report, skip, func(c *Call, _ int) error {
+		calls = append(calls, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calls, report
+}
+
+func checkRejections(t *testing.T, report *Report, sheet string, want []Rejection) {
+	t.Helper()
+	if len(report.Rejected) != len(want) {
+		t.Fatalf("rejected = %+v, want %d rows", report.Rejected, len(want))
+	}
+	for i, w := range want {
+		got := report.Rejected[i]
+		if got.Sheet != sheet || got.Row != w.Row || got.Column != w.Column || got.Value != w.Value || got.Reason != w.Reason {
+			t.Errorf("rejection %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package dgraph_imei
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColumnSpec describes how a call field is found in a sheet.
+type ColumnSpec struct {
+	// Aliases are the header texts the column may have. Matching ignores
+	// case and surrounding spaces. The field name itself always matches.
+	Aliases []string `json:"aliases" yaml:"aliases"`
+	// Optional columns may be missing from the sheet or left empty, in
+	// which case the field keeps its zero value. Only latitude, longitude
+	// and duration can be optional.
+	Optional bool `json:"optional" yaml:"optional"`
+}
+
+// MappingProfile describes the layout of an operator's export: which sheets
+// hold the calls, where their header row is and how the columns are named.
+// Columns is keyed by the field names MSDIN, IMEI_FROM, latitude,
+// longitude, duration, IMEI_TO and call_time.
+type MappingProfile struct {
+	// Sheet is the name of the only sheet to read. At most one of Sheet,
+	// SheetPattern and SheetIndex may be set; when none is, every sheet of
+	// the workbook is read. Sheets without a recognisable call header are
+	// skipped and listed in the report.
+	Sheet string `json:"sheet" yaml:"sheet"`
+	// SheetPattern selects the sheets whose names match a shell pattern,
+	// e.g. "Calls *" or "2024-??". Matching ignores case.
+	SheetPattern string `json:"sheet_pattern" yaml:"sheet_pattern"`
+	// SheetIndex is the 1-based position of the only sheet to read.
+	SheetIndex int `json:"sheet_index" yaml:"sheet_index"`
+	// HeaderRow is the 1-based number of the header row. Rows above it are
+	// ignored. Zero means the first row.
+	HeaderRow int                   `json:"header_row" yaml:"header_row"`
+	Columns   map[string]ColumnSpec `json:"columns" yaml:"columns"`
+	// CallTimeLayouts are the Go time layouts tried in order for call_time
+	// values stored as text, e.g. "02.01.2006 15:04". A built-in list of
+	// common layouts is used when empty. Excel date cells need no layout.
+	CallTimeLayouts []string `json:"call_time_layouts" yaml:"call_time_layouts"`
+	// TimeZone is the IANA name of the zone call times without an offset
+	// are recorded in, e.g. "Europe/Moscow". UTC when empty.
+	TimeZone string `json:"time_zone" yaml:"time_zone"`
+}
+
+// optionalFields lists the fields a Call can do without.
+var optionalFields = map[int]bool{
+	colLatitude:  true,
+	colLongitude: true,
+	colDuration:  true,
+}
+
+// DefaultMappingProfile reads every sheet with a header row naming
+// the columns after the Call fields, in any order.
+func DefaultMappingProfile() *MappingProfile {
+	return &MappingProfile{}
+}
+
+// LoadMappingProfile reads a profile from a .json, .yaml or .yml file.
+func LoadMappingProfile(path string) (*MappingProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &MappingProfile{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, p)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, p)
+	default:
+		return nil, fmt.Errorf("unsupported mapping profile format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping profile %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mapping profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that the profile only refers to known fields and that
+// required fields are not marked optional.
+func (p *MappingProfile) Validate() error {
+	if p.SheetIndex < 0 {
+		return fmt.Errorf("sheet_index must not be negative")
+	}
+	selectors := 0
+	for _, set := range []bool{p.Sheet != "", p.SheetPattern != "", p.SheetIndex > 0} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors > 1 {
+		return fmt.Errorf("only one of sheet, sheet_pattern and sheet_index may be set")
+	}
+	if _, err := path.Match(p.SheetPattern, ""); err != nil {
+		return fmt.Errorf("invalid sheet_pattern: %w", err)
+	}
+	if p.HeaderRow < 0 {
+		return fmt.Errorf("header_row must not be negative")
+	}
+	if _, err := p.location(); err != nil {
+		return err
+	}
+	for field, spec := range p.Columns {
+		col := fieldIndex(field)
+		if col < 0 {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if spec.Optional && !optionalFields[col] {
+			return fmt.Errorf("field %s cannot be optional", field)
+		}
+	}
+	return nil
+}
+
+// sheetNames returns the names of the sheets the profile selects, in
+// workbook order.
+func (p *MappingProfile) sheetNames(sheets []string) ([]string, error) {
+	switch {
+	case p.Sheet != "":
+		for _, name := range sheets {
+			if name == p.Sheet {
+				return []string{name}, nil
+			}
+		}
+		return nil, fmt.Errorf("sheet %q not found", p.Sheet)
+	case p.SheetPattern != "":
+		var names []string
+		for _, name := range sheets {
+			matched, err := path.Match(strings.ToLower(p.SheetPattern), strings.ToLower(name))
+			if err != nil {
+				return nil, fmt.Errorf("invalid sheet_pattern: %w", err)
+			}
+			if matched {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no sheet matches %q", p.SheetPattern)
+		}
+		return names, nil
+	case p.SheetIndex > 0:
+		if p.SheetIndex > len(sheets) {
+			return nil, fmt.Errorf("sheet %d not found, the workbook has %d sheets", p.SheetIndex, len(sheets))
+		}
+		return sheets[p.SheetIndex-1 : p.SheetIndex], nil
+	default:
+		return sheets, nil
+	}
+}
+
+// location returns the time zone of call times without an offset.
+func (p *MappingProfile) location() (*time.Location, error) {
+	if p.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time_zone: %w", err)
+	}
+	return loc, nil
+}
+
+func (p *MappingProfile) callTimeLayouts() []string {
+	if len(p.CallTimeLayouts) > 0 {
+		return p.CallTimeLayouts
+	}
+	return defaultCallTimeLayouts
+}
+
+// headerRow returns the 1-based number of the header row.
+func (p *MappingProfile) headerRow() int {
+	return max(p.HeaderRow, 1)
+}
+
+// layout matches the header row of a sheet against the profile.
+func (p *MappingProfile) layout(header []string) (*columnLayout, error) {
+	l := &columnLayout{
+		index:    make([]int, len(columnNames)),
+		header:   make([]string, len(columnNames)),
+		optional: make([]bool, len(columnNames)),
+	}
+	for col, field := range columnNames {
+		spec := p.Columns[field]
+		l.index[col] = -1
+		l.header[col] = field
+		l.optional[col] = spec.Optional
+		aliases := append([]string{field}, spec.Aliases...)
+		for i, text := range header {
+			if matchesAlias(text, aliases) {
+				l.index[col], l.header[col] = i, text
+				break
+			}
+		}
+		if l.index[col] < 0 && !spec.Optional {
+			return nil, fmt.Errorf("no column for field %s", field)
+		}
+	}
+	return l, nil
+}
+
+// fieldLayout is the layout of records that already hold the fields in the
+// order of columnNames.
+func (p *MappingProfile) fieldLayout() *columnLayout {
+	l := &columnLayout{
+		index:    make([]int, len(columnNames)),
+		header:   columnNames,
+		optional: make([]bool, len(columnNames)),
+	}
+	for col, field := range columnNames {
+		l.index[col] = col
+		l.optional[col] = p.Columns[field].Optional
+	}
+	return l
+}
+
+func matchesAlias(text string, aliases []string) bool {
+	text = strings.TrimSpace(text)
+	for _, alias := range aliases {
+		if strings.EqualFold(text, strings.TrimSpace(alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldIndex(field string) int {
+	for col, name := range columnNames {
+		if name == field {
+			return col
+		}
+	}
+	return -1
+}
+
+// columnLayout tells where each call field is in the rows of a sheet.
+type columnLayout struct {
+	index    []int    // column of each field, -1 when an optional column is missing
+	header   []string // header text of each field, used in rejections
+	optional []bool
+}
+
+// cells reorders a row into the order of columnNames.
+func (l *columnLayout) cells(row []string) []string {
+	cells := make([]string, len(l.index))
+	for col, i := range l.index {
+		if i >= 0 && i < len(row) {
+			cells[col] = row[i]
+		}
+	}
+	return cells
+}
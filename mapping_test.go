@@ -0,0 +1,235 @@
+package dgraph_imei
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMappingProfile(t *testing.T) {
+	want := &MappingProfile{
+		SheetPattern: "Звонки *",
+		HeaderRow:    3,
+		Columns: map[string]ColumnSpec{
+			"MSDIN":    {Aliases: []string{"Номер", "Абонент"}},
+			"duration": {Aliases: []string{"Длительность"}, Optional: true},
+		},
+		CallTimeLayouts: []string{"02.01.2006 15:04"},
+		TimeZone:        "Europe/Moscow",
+	}
+	files := map[string]string{
+		"profile.json": `{
+			"sheet_pattern": "Звонки *",
+			"header_row": 3,
+			"columns": {
+				"MSDIN": {"aliases": ["Номер", "Абонент"]},
+				"duration": {"aliases": ["Длительность"], "optional": true}
+			},
+			"call_time_layouts": ["02.01.2006 15:04"],
+			"time_zone": "Europe/Moscow"
+		}`,
+		"profile.YAML": `
+sheet_pattern: Звонки *
+header_row: 3
+columns:
+  MSDIN:
+    aliases: [Номер, Абонент]
+  duration:
+    aliases: [Длительность]
+    optional: true
+call_time_layouts: ["02.01.2006 15:04"]
+time_zone: Europe/Moscow
+`,
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadMappingProfile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: profile = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestLoadMappingProfileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"profile.toml", `sheet = "Calls"`, `unsupported mapping profile format ".toml"`},
+		{"broken.json", `{"sheet": `, "failed to parse mapping profile"},
+		{"invalid.yaml", "columns:\n  IMEI: {}\n", `invalid mapping profile`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadMappingProfile(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := LoadMappingProfile(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: error = %v, want a not exist error", err)
+	}
+}
+
+func TestMappingProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile MappingProfile
+		err     string
+	}{
+		{name: "default"},
+		{
+			name:    "valid",
+			profile: MappingProfile{SheetIndex: 2, HeaderRow: 2, Columns: map[string]ColumnSpec{"latitude": {Optional: true}}, TimeZone: "Asia/Yekaterinburg"},
+		},
+		{
+			name:    "sheet and pattern",
+			profile: MappingProfile{Sheet: "Calls", SheetPattern: "Calls *"},
+			err:     "only one of sheet, sheet_pattern and sheet_index may be set",
+		},
+		{
+			name:    "pattern and index",
+			profile: MappingProfile{SheetPattern: "Calls *", SheetIndex: 1},
+			err:     "only one of sheet, sheet_pattern and sheet_index may be set",
+		},
+		{
+			name:    "negative index",
+			profile: MappingProfile{SheetIndex: -1},
+			err:     "sheet_index must not be negative",
+		},
+		{
+			name:    "bad pattern",
+			profile: MappingProfile{SheetPattern: "Calls ["},
+			err:     "invalid sheet_pattern",
+		},
+		{
+			name:    "negative header row",
+			profile: MappingProfile{HeaderRow: -2},
+			err:     "header_row must not be negative",
+		},
+		{
+			name:    "unknown field",
+			profile: MappingProfile{Columns: map[string]ColumnSpec{"IMEI": {Aliases: []string{"IMEI"}}}},
+			err:     `unknown field "IMEI"`,
+		},
+		{
+			name:    "required field optional",
+			profile: MappingProfile{Columns: map[string]ColumnSpec{"call_time": {Optional: true}}},
+			err:     "field call_time cannot be optional",
+		},
+		{
+			name:    "bad time zone",
+			profile: MappingProfile{TimeZone: "Europe/Atlantis"},
+			err:     "invalid time_zone",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.profile.Validate()
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestMappingProfileHeaderRow(t *testing.T) {
+	data := "Выгрузка звонков;;;;;;\n" +
+		"Оператор;МТС;;;;;\n" +
+		"MSDIN;IMEI_FROM;latitude;longitude;duration;IMEI_TO;call_time\n" +
+		"89161234567;490154203237518;55,7558;37,6173;12;356938035643809;2024-03-16T10:04:05Z\n"
+	read := func(mapping *MappingProfile) ([]*Call, *Report, error) {
+		parser, err := newRowParser(mapping, DefaultMSISDNNormalizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := newCSVSource(strings.NewReader(data), "calls.csv", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var calls []*Call
+		report := &Report{}
+		err = readRecords(context.Background(), src, parser, report, 0, func(c *Call, _ int) error {
+			calls = append(calls, c)
+			return nil
+		})
+		return calls, report, err
+	}
+
+	calls, report, err := read(&MappingProfile{HeaderRow: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || len(report.Rejected) != 0 {
+		t.Fatalf("calls = %d, rejected = %+v, want 1 call", len(calls), report.Rejected)
+	}
+	if c := calls[0]; c.Latitude != 55.7558 || c.Duration != 12 {
+		t.Errorf("call = %+v", *c)
+	}
+
+	// The same file read with the header in the first row has no header.
+	if _, _, err := read(DefaultMappingProfile()); err == nil || !strings.Contains(err.Error(), "no column for field MSDIN") {
+		t.Errorf("header in row 1: error = %v", err)
+	}
+}
+
+func TestMappingProfileLayout(t *testing.T) {
+	mapping := &MappingProfile{Columns: map[string]ColumnSpec{
+		"MSDIN":     {Aliases: []string{"Номер"}},
+		"latitude":  {Optional: true},
+		"longitude": {Optional: true},
+		"duration":  {Aliases: []string{"Длительность"}, Optional: true},
+	}}
+	header := []string{" номер ", "IMEI_TO", "Длительность", "call_time", "IMEI_FROM"}
+	l, err := mapping.layout(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 4, -1, -1, 2, 1, 3}; !reflect.DeepEqual(l.index, want) {
+		t.Errorf("index = %v, want %v", l.index, want)
+	}
+	if want := []string{" номер ", "IMEI_FROM", "latitude", "longitude", "Длительность", "IMEI_TO", "call_time"}; !reflect.DeepEqual(l.header, want) {
+		t.Errorf("header = %q, want %q", l.header, want)
+	}
+
+	// The missing optional columns are empty, and empty optional values
+	// are marked as missing.
+	parser, err := newRowParser(mapping, DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, rej := parser.parseRow(l, []string{"89161234567", "356938035643809", "", "2024-03-16T10:04:05Z", "490154203237518"})
+	if rej != nil {
+		t.Fatalf("rejected: %+v", rej)
+	}
+	if call.HasLatitude || call.HasLongitude || call.HasDuration || call.Msdin != "+79161234567" {
+		t.Errorf("call = %+v", *call)
+	}
+
+	// Without the aliases and the optional flags the header is incomplete.
+	if _, err := DefaultMappingProfile().layout(header); err == nil || err.Error() != "no column for field MSDIN" {
+		t.Errorf("default profile: error = %v", err)
+	}
+	delete(mapping.Columns, "latitude")
+	if _, err := mapping.layout(header); err == nil || err.Error() != "no column for field latitude" {
+		t.Errorf("required latitude: error = %v", err)
+	}
+}
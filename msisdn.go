@@ -0,0 +1,101 @@
+package dgraph_imei
+
+import "strings"
+
+// MSISDNNormalizer converts subscriber numbers written in national or
+// international format to E.164, e.g. "+79161234567".
+type MSISDNNormalizer struct {
+	// CountryCode is the calling code assumed for numbers without one,
+	// e.g. "7".
+	CountryCode string
+	// TrunkPrefix is dialled instead of the country code in national
+	// format, e.g. "8" in Russia or "0" in most of Europe.
+	TrunkPrefix string
+	// InternationalPrefix is dialled before a country code instead of "+",
+	// e.g. "00".
+	InternationalPrefix string
+	// NationalLength is the length of a national significant number. When
+	// set, numbers without "+" or the international prefix must be national
+	// numbers of that length, with or without the trunk prefix or country
+	// code. When zero, such numbers are taken as international unless they
+	// start with the trunk prefix.
+	NationalLength int
+}
+
+// DefaultMSISDNNormalizer follows the Russian numbering plan.
+var DefaultMSISDNNormalizer = MSISDNNormalizer{
+	CountryCode:         "7",
+	TrunkPrefix:         "8",
+	InternationalPrefix: "810",
+	NationalLength:      10,
+}
+
+// E.164 numbers have at most 15 digits including the country code. The
+// lower bound rejects short service numbers, which are not subscribers.
+const (
+	minMSISDNDigits = 8
+	maxMSISDNDigits = 15
+)
+
+// Normalize returns s in E.164 format. Spaces, dashes, dots and
+// parentheses used as separators are ignored.
+func (n MSISDNNormalizer) Normalize(s string) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(s)
+	if len(digits) == 0 {
+		return "", rejectf(ReasonEmptyValue, "the string is empty")
+	}
+	international := strings.HasPrefix(digits, "+")
+	digits = strings.TrimPrefix(digits, "+")
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", rejectf(ReasonNotDigits, "MSISDN contains a non-digit character %q", r)
+		}
+	}
+
+	switch {
+	case international:
+	case n.isNational(digits):
+		digits = n.CountryCode + digits
+	case n.hasInternationalPrefix(digits):
+		digits = digits[len(n.InternationalPrefix):]
+	case n.hasTrunkPrefix(digits):
+		digits = n.CountryCode + digits[len(n.TrunkPrefix):]
+	case n.NationalLength > 0 && !n.hasCountryCode(digits):
+		return "", rejectf(ReasonInvalidMSISDN, "MSISDN %q does not match the national numbering plan", s)
+	}
+
+	if len(digits) < minMSISDNDigits || len(digits) > maxMSISDNDigits {
+		return "", rejectf(ReasonInvalidMSISDN, "MSISDN must have %d to %d digits, got %d", minMSISDNDigits, maxMSISDNDigits, len(digits))
+	}
+	if digits[0] == '0' {
+		return "", rejectf(ReasonInvalidMSISDN, "country code cannot start with 0")
+	}
+	return "+" + digits, nil
+}
+
+// isNational reports whether digits is a national significant number
+// written without any prefix.
+func (n MSISDNNormalizer) isNational(digits string) bool {
+	return n.NationalLength > 0 && len(digits) == n.NationalLength
+}
+
+// hasInternationalPrefix reports whether digits starts with the
+// international prefix. Where that prefix begins with the trunk prefix, as
+// "810" does with "8", a number of national length is a national one.
+func (n MSISDNNormalizer) hasInternationalPrefix(digits string) bool {
+	if n.InternationalPrefix == "" || !strings.HasPrefix(digits, n.InternationalPrefix) {
+		return false
+	}
+	return n.NationalLength == 0 || !n.hasTrunkPrefix(digits)
+}
+
+func (n MSISDNNormalizer) hasTrunkPrefix(digits string) bool {
+	if n.TrunkPrefix == "" || !strings.HasPrefix(digits, n.TrunkPrefix) {
+		return false
+	}
+	return n.NationalLength == 0 || len(digits) == len(n.TrunkPrefix)+n.NationalLength
+}
+
+func (n MSISDNNormalizer) hasCountryCode(digits string) bool {
+	return strings.HasPrefix(digits, n.CountryCode) && len(digits) == len(n.CountryCode)+n.NationalLength
+}
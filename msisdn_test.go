@@ -0,0 +1,45 @@
+package dgraph_imei
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMSISDNNormalize(t *testing.T) {
+	german := MSISDNNormalizer{CountryCode: "49", TrunkPrefix: "0", InternationalPrefix: "00"}
+	tests := []struct {
+		n      MSISDNNormalizer
+		in     string
+		want   string
+		reason RejectReason
+	}{
+		{n: DefaultMSISDNNormalizer, in: "79161234567", want: "+79161234567"},
+		{n: DefaultMSISDNNormalizer, in: "+79161234567", want: "+79161234567"},
+		{n: DefaultMSISDNNormalizer, in: "89161234567", want: "+79161234567"},
+		{n: DefaultMSISDNNormalizer, in: "9161234567", want: "+79161234567"},
+		{n: DefaultMSISDNNormalizer, in: "8 (916) 123-45-67", want: "+79161234567"},
+		{n: DefaultMSISDNNormalizer, in: "810380501234567", want: "+380501234567"},
+		{n: DefaultMSISDNNormalizer, in: "+380501234567", want: "+380501234567"},
+		{n: DefaultMSISDNNormalizer, in: "12345", reason: ReasonInvalidMSISDN},
+		{n: DefaultMSISDNNormalizer, in: "380501234567", reason: ReasonInvalidMSISDN},
+		{n: DefaultMSISDNNormalizer, in: "BAD_MSDIN", reason: ReasonNotDigits},
+		{n: DefaultMSISDNNormalizer, in: "", reason: ReasonEmptyValue},
+		{n: german, in: "015112345678", want: "+4915112345678"},
+		{n: german, in: "004915112345678", want: "+4915112345678"},
+		{n: german, in: "4915112345678", want: "+4915112345678"},
+		{n: german, in: "+0123456789", reason: ReasonInvalidMSISDN},
+	}
+	for _, tt := range tests {
+		got, err := tt.n.Normalize(tt.in)
+		if tt.reason != "" {
+			var re *reasonError
+			if !errors.As(err, &re) || re.reason != tt.reason {
+				t.Errorf("Normalize(%q) error = %v, want reason %s", tt.in, err, tt.reason)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package dgraph_imei
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// XML Schema datatypes understood by Dgraph for typed literals.
+const (
+	xsString   = "xs:string"
+	xsInt      = "xs:int"
+	xsFloat    = "xs:float"
+	xsDateTime = "xs:dateTime"
+)
+
+// identPattern restricts predicate, blank node and variable names to the
+// characters this package uses. They never come from user input, so a
+// mismatch is a programming error.
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// uidPattern matches a Dgraph uid as returned by queries.
+var uidPattern = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+
+// nquadNode is the subject or object of an N-Quad, already rendered in its
+// N-Quad form. It can only be created by blankNode, uidVar and uidNode.
+type nquadNode struct {
+	s string
+}
+
+// blankNode refers to a node created by the mutation, e.g. _:c0.
+func blankNode(name string) nquadNode {
+	mustBeIdent(name)
+	return nquadNode{"_:" + name}
+}
+
+// uidVar refers to the nodes bound to a query variable of an upsert block.
+func uidVar(name string) nquadNode {
+	mustBeIdent(name)
+	return nquadNode{"uid(" + name + ")"}
+}
+
+// uidNode refers to an existing node by its uid.
+func uidNode(uid string) (nquadNode, error) {
+	if !uidPattern.MatchString(uid) {
+		return nquadNode{}, fmt.Errorf("invalid uid %q", uid)
+	}
+	return nquadNode{"<" + uid + ">"}, nil
+}
+
+// nquadBuilder accumulates N-Quads for a mutation. Literal values are always
+// quoted and escaped, so no value can end the current quad or start a new one.
+type nquadBuilder struct {
+	buf strings.Builder
+}
+
+// edge adds a quad linking subject to object.
+func (b *nquadBuilder) edge(subject nquadNode, predicate string, object nquadNode) {
+	b.quad(subject, predicate, object.s)
+}
+
+// literal adds a quad with an untyped string value.
+func (b *nquadBuilder) literal(subject nquadNode, predicate, value string) {
+	b.quad(subject, predicate, quoteLiteral(value))
+}
+
+// typed adds a quad with a value of the given XML Schema datatype.
+func (b *nquadBuilder) typed(subject nquadNode, predicate, value, datatype string) {
+	b.quad(subject, predicate, quoteLiteral(value)+"^^<"+datatype+">")
+}
+
+// float adds a quad with an xs:float value.
+func (b *nquadBuilder) float(subject nquadNode, predicate string, value float64) {
+	b.typed(subject, predicate, strconv.FormatFloat(value, 'f', -1, 64), xsFloat)
+}
+
+// wildcard adds a quad matching every value of the predicate, for use in
+// deletions.
+func (b *nquadBuilder) wildcard(subject nquadNode, predicate string) {
+	b.quad(subject, predicate, "*")
+}
+
+// wildcardNode adds a quad matching every predicate of the node, for use in
+// deletions of whole nodes.
+func (b *nquadBuilder) wildcardNode(subject nquadNode) {
+	b.buf.WriteString(subject.s)
+	b.buf.WriteString(" * * .\n")
+}
+
+func (b *nquadBuilder) quad(subject nquadNode, predicate, object string) {
+	mustBeIdent(predicate)
+	b.buf.WriteString(subject.s)
+	b.buf.WriteString(" <")
+	b.buf.WriteString(predicate)
+	b.buf.WriteString("> ")
+	b.buf.WriteString(object)
+	b.buf.WriteString(" .\n")
+}
+
+func (b *nquadBuilder) bytes() []byte {
+	return []byte(b.buf.String())
+}
+
+// quoteLiteral renders s as a double quoted N-Quad literal. Quotes and
+// backslashes are escaped and every control character is written as a
+// \u escape, so the result is always a single line. Invalid UTF-8 is
+// replaced with U+FFFD.
+func quoteLiteral(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func mustBeIdent(name string) {
+	if !identPattern.MatchString(name) {
+		panic(fmt.Sprintf("dgraph_imei: invalid N-Quad identifier %q", name))
+	}
+}
@@ -0,0 +1,200 @@
+package dgraph_imei
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var injectionSeeds = []string{
+	"",
+	"12345",
+	`" .`,
+	"\" .\n_:x <dgraph.type> \"pwned\" .\n",
+	`\" . uid(d0) <IMEI> "x`,
+	"2024-03-16T00:04:05\"^^<xs:string> .\r\n<0x1> * * .",
+	"line separator\x00nul\x7fdel",
+	"\xff\xfeinvalid utf8",
+	`$d0) { uid } }`,
+}
+
+func FuzzQuoteLiteral(f *testing.F) {
+	for _, s := range injectionSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		q := quoteLiteral(s)
+		if strings.ContainsAny(q, "\r\n") {
+			t.Fatalf("literal %q spans several lines", q)
+		}
+		if end := literalEnd(q); end != len(q) {
+			t.Fatalf("literal %q ends at %d, want %d", q, end, len(q))
+		}
+		got, err := strconv.Unquote(q)
+		if err != nil {
+			t.Fatalf("cannot unquote %q: %v", q, err)
+		}
+		if want := string([]rune(s)); got != want {
+			t.Fatalf("literal round trip: got %q, want %q", got, want)
+		}
+	})
+}
+
+func FuzzBuildUpsertRequest(f *testing.F) {
+	for _, s := range injectionSeeds {
+		f.Add(s, s, "2222222", s, 30.7346, 79.0669, 12.0)
+	}
+	f.Add("79161234567", "490154203237518", "356938035643809", "2024-03-16T00:04:05Z", 30.7346, 79.0669, 12.0)
+	f.Fuzz(func(t *testing.T, msdin, imeiFrom, imeiTo, callTime string, lat, lng, dur float64) {
+		call := &Call{
+			Msdin:        msdin,
+			ImeiFrom:     imeiFrom,
+			ImeiTo:       imeiTo,
+			CallTime:     callTime,
+			Latitude:     lat,
+			Longitude:    lng,
+			Duration:     dur,
+			HasLatitude:  true,
+			HasLongitude: true,
+			HasDuration:  true,
+		}
+		// The reference call has the same shape as the fuzzed one but
+		// harmless values, so both requests must have the same structure.
+		ref := &Call{Msdin: "1", ImeiFrom: "2", ImeiTo: "3", CallTime: "4", HasLatitude: true, HasLongitude: true, HasDuration: true}
+		if isValidIMEI(imeiFrom) {
+			ref.ImeiFrom = "490154203237518"
+		}
+		if isValidIMEI(imeiTo) {
+			ref.ImeiTo = "356938035643809"
+		}
+		if imeiFrom == imeiTo {
+			ref.ImeiTo = ref.ImeiFrom
+		}
+
+		got := buildUpsertRequest([]*Call{call}, nil)
+		want := buildUpsertRequest([]*Call{ref}, nil)
+
+		if got.Query != want.Query {
+			t.Fatalf("query depends on cell values:\n%s\nwant:\n%s", got.Query, want.Query)
+		}
+		for name, value := range want.Vars {
+			w := map[string]string{ref.Msdin: msdin, ref.ImeiFrom: imeiFrom, ref.ImeiTo: imeiTo, ref.Key(): call.Key()}[value]
+			if got.Vars[name] != w {
+				t.Fatalf("variable %s = %q, want %q", name, got.Vars[name], w)
+			}
+		}
+
+		gotQuads := parseNQuads(t, got.Mutations[0].SetNquads)
+		wantQuads := parseNQuads(t, want.Mutations[0].SetNquads)
+		if len(gotQuads) != len(wantQuads) {
+			t.Fatalf("mutation has %d quads, want %d", len(gotQuads), len(wantQuads))
+		}
+		imeis := []string{imeiFrom, imeiTo}
+		if imeiFrom == imeiTo {
+			imeis = imeis[:1]
+		}
+		values := map[string][]string{"IMEI": imeis, "MSDIN": {msdin}, "call_time": {callTime}, "call_key": {call.Key()}}
+		for i, q := range gotQuads {
+			w := wantQuads[i]
+			if q.subject != w.subject || q.predicate != w.predicate {
+				t.Fatalf("quad %d is %s <%s>, want %s <%s>", i, q.subject, q.predicate, w.subject, w.predicate)
+			}
+			if q.literal != w.literal || q.datatype != w.datatype {
+				t.Fatalf("quad %d has object %s%s, want %s%s", i, q.object, q.datatype, w.object, w.datatype)
+			}
+			if !q.literal {
+				if q.object != w.object {
+					t.Fatalf("quad %d links to %s, want %s", i, q.object, w.object)
+				}
+				continue
+			}
+			if vs := values[q.predicate]; len(vs) > 0 {
+				if want := string([]rune(vs[0])); q.object != want {
+					t.Fatalf("quad %d stores %q, want %q", i, q.object, want)
+				}
+				values[q.predicate] = vs[1:]
+			}
+		}
+	})
+}
+
+type parsedQuad struct {
+	subject, predicate, object, datatype string
+	literal                              bool
+}
+
+// parseNQuads is a strict parser for the subset of N-Quads produced by
+// nquadBuilder. It fails the test on anything it does not recognise.
+func parseNQuads(t *testing.T, data []byte) []parsedQuad {
+	t.Helper()
+	var quads []parsedQuad
+	lines := strings.Split(string(data), "\n")
+	if lines[len(lines)-1] != "" {
+		t.Fatalf("mutation does not end with a newline: %q", data)
+	}
+	for _, line := range lines[:len(lines)-1] {
+		var q parsedQuad
+		rest, ok := strings.CutSuffix(line, " .")
+		if !ok {
+			t.Fatalf("quad %q is not terminated", line)
+		}
+		q.subject, rest, ok = strings.Cut(rest, " ")
+		if !ok || !isNode(q.subject) {
+			t.Fatalf("quad %q has invalid subject", line)
+		}
+		pred, rest, ok := strings.Cut(rest, " ")
+		if !ok || !strings.HasPrefix(pred, "<") || !strings.HasSuffix(pred, ">") || !identPattern.MatchString(pred[1:len(pred)-1]) {
+			t.Fatalf("quad %q has invalid predicate", line)
+		}
+		q.predicate = pred[1 : len(pred)-1]
+		if isNode(rest) {
+			q.object = rest
+		} else {
+			end := literalEnd(rest)
+			if end < 0 {
+				t.Fatalf("quad %q has invalid object", line)
+			}
+			value, err := strconv.Unquote(rest[:end])
+			if err != nil {
+				t.Fatalf("quad %q has invalid literal: %v", line, err)
+			}
+			q.literal, q.object, q.datatype = true, value, rest[end:]
+			if q.datatype != "" && !(strings.HasPrefix(q.datatype, "^^<xs:") && identPattern.MatchString(strings.TrimSuffix(q.datatype[6:], ">"))) {
+				t.Fatalf("quad %q has invalid datatype", line)
+			}
+		}
+		quads = append(quads, q)
+	}
+	return quads
+}
+
+func isNode(s string) bool {
+	switch {
+	case strings.HasPrefix(s, "_:"):
+		return identPattern.MatchString(s[2:])
+	case strings.HasPrefix(s, "uid(") && strings.HasSuffix(s, ")"):
+		return identPattern.MatchString(s[4 : len(s)-1])
+	case strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">"):
+		return uidPattern.MatchString(s[1 : len(s)-1])
+	}
+	return false
+}
+
+// literalEnd returns the index just past the closing quote of the literal
+// s starts with, following the same rules as the Dgraph lexer.
+func literalEnd(s string) int {
+	if !strings.HasPrefix(s, `"`) {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '\r', '\n':
+			return -1
+		}
+	}
+	return -1
+}
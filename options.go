@@ -0,0 +1,99 @@
+package dgraph_imei
+
+import (
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Option configures a FileClient created by NewClient.
+type Option func(*FileClient)
+
+// WithBatchSize sets how many calls are written to Dgraph in one upsert
+// request. Non-positive values fall back to the default.
+func WithBatchSize(n int) Option {
+	return func(c *FileClient) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithMSISDNNormalizer sets the numbering plan used to convert MSDIN cells
+// to E.164. DefaultMSISDNNormalizer is used otherwise.
+func WithMSISDNNormalizer(n MSISDNNormalizer) Option {
+	return func(c *FileClient) {
+		c.msisdn = n
+	}
+}
+
+// WithMappingProfile sets how sheets and columns are found in the ingested
+// files. DefaultMappingProfile is used otherwise. NewClient
+// fails if the profile is nil or invalid.
+func WithMappingProfile(p *MappingProfile) Option {
+	return func(c *FileClient) {
+		c.mapping = p
+	}
+}
+
+// WithWorkers sets how many batches are written to Dgraph concurrently.
+// Calls are sharded between the workers by MSDIN. Non-positive values fall
+// back to the default of one worker.
+func WithWorkers(n int) Option {
+	return func(c *FileClient) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithConflictRetry sets how often a transaction aborted by a conflict with
+// another transaction is retried, and how long to wait before the first
+// retry. The wait doubles with every retry. Zero retries disable retrying.
+func WithConflictRetry(maxRetries int, initialBackoff time.Duration) Option {
+	return func(c *FileClient) {
+		c.retry = retryPolicy{maxRetries: max(maxRetries, 0), initialBackoff: initialBackoff}
+	}
+}
+
+// WithDgraphTLS connects to Dgraph over TLS, verifying the server with the
+// CA of files and sending the client certificate of files if Dgraph
+// requires mutual TLS.
+func WithDgraphTLS(files TLSFiles) Option {
+	return func(c *FileClient) {
+		c.dgraphTLS = &files
+	}
+}
+
+// WithXlsxTLS connects to the XlsxService server over TLS, like
+// WithDgraphTLS.
+func WithXlsxTLS(files TLSFiles) Option {
+	return func(c *FileClient) {
+		c.xlsxTLS = &files
+	}
+}
+
+// WithXlsxCredentials sends creds with every request to the XlsxService
+// server, like BearerToken(token) for servers requiring authentication.
+func WithXlsxCredentials(creds credentials.PerRPCCredentials) Option {
+	return func(c *FileClient) {
+		c.xlsxCreds = creds
+	}
+}
+
+// WithDgraphLogin logs in to Dgraph as an ACL user. The login is refreshed
+// before it expires for as long as the client is open.
+func WithDgraphLogin(user, password string) Option {
+	return func(c *FileClient) {
+		c.login = &dgraphLogin{user: user, password: password}
+	}
+}
+
+// WithNamespace logs in to a Dgraph namespace instead of the default one,
+// so the calls of different investigations are kept apart. It requires
+// WithDgraphLogin.
+func WithNamespace(namespace uint64) Option {
+	return func(c *FileClient) {
+		c.namespace = namespace
+	}
+}
@@ -0,0 +1,210 @@
+package dgraph_imei
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/types"
+)
+
+// parquetBatchSize is the number of rows decoded from a Parquet file at
+// once.
+const parquetBatchSize = 1000
+
+// parquetSource reads Parquet files with one call per row, with columns
+// named after the JSON names of the Call fields. Rows are read without a
+// predefined schema, so extra columns are ignored and missing ones are
+// reported like missing JSON fields.
+type parquetSource struct {
+	name    string
+	file    source.ParquetFile
+	pr      *reader.ParquetReader
+	columns []parquetColumn
+	rows    []any
+	row     int
+}
+
+// parquetColumn is a top-level column of a Parquet file, in the order of
+// the fields of the rows read from it.
+type parquetColumn struct {
+	name string
+	// unit is the resolution of INT64 timestamp columns, zero otherwise.
+	unit time.Duration
+	// int96 is set for legacy INT96 timestamp columns.
+	int96 bool
+}
+
+// newParquetSource reads the Parquet data of file, which stays open until
+// the caller closes it.
+func newParquetSource(file *os.File, name string) (*parquetSource, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pf := newParquetFile(file, info.Size())
+	pr, err := reader.NewParquetReader(pf, nil, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet data: %w", err)
+	}
+	return &parquetSource{name: name, file: pf, pr: pr, columns: parquetColumns(pr.Footer.Schema)}, nil
+}
+
+// parquetFile reads Parquet data from an already opened file. The reader
+// opens a handle per column; they all read the same file descriptor through
+// their own section, so the file is never opened again by path.
+type parquetFile struct {
+	*io.SectionReader
+	r    io.ReaderAt
+	size int64
+}
+
+func newParquetFile(r io.ReaderAt, size int64) *parquetFile {
+	return &parquetFile{SectionReader: io.NewSectionReader(r, 0, size), r: r, size: size}
+}
+
+func (f *parquetFile) Open(string) (source.ParquetFile, error) {
+	return newParquetFile(f.r, f.size), nil
+}
+
+func (f *parquetFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("parquet data is read-only")
+}
+
+func (f *parquetFile) Write([]byte) (int, error) {
+	return 0, errors.New("parquet data is read-only")
+}
+
+// Close does nothing; the file belongs to the caller.
+func (f *parquetFile) Close() error { return nil }
+
+// parquetColumns lists the children of the schema root. Nested columns are
+// kept as a whole; they cannot be decoded into a Call anyway.
+func parquetColumns(schema []*parquet.SchemaElement) []parquetColumn {
+	var columns []parquetColumn
+	for i := 1; i < len(schema); i = skipParquetSubtree(schema, i) {
+		el := schema[i]
+		col := parquetColumn{name: el.GetName()}
+		switch {
+		case el.GetType() == parquet.Type_INT96:
+			col.int96 = true
+		case el.GetLogicalType() != nil && el.GetLogicalType().IsSetTIMESTAMP():
+			switch unit := el.GetLogicalType().GetTIMESTAMP().GetUnit(); {
+			case unit.IsSetMILLIS():
+				col.unit = time.Millisecond
+			case unit.IsSetMICROS():
+				col.unit = time.Microsecond
+			case unit.IsSetNANOS():
+				col.unit = time.Nanosecond
+			}
+		case el.IsSetConvertedType() && el.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MILLIS:
+			col.unit = time.Millisecond
+		case el.IsSetConvertedType() && el.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MICROS:
+			col.unit = time.Microsecond
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// skipParquetSubtree returns the index of the element following the
+// element at i and all of its descendants.
+func skipParquetSubtree(schema []*parquet.SchemaElement, i int) int {
+	for pending := 1; pending > 0 && i < len(schema); i++ {
+		pending += int(schema[i].GetNumChildren()) - 1
+	}
+	return i
+}
+
+func (s *parquetSource) next() (record, error) {
+	if len(s.rows) == 0 {
+		if int64(s.row) >= s.pr.GetNumRows() {
+			return record{}, io.EOF
+		}
+		rows, err := s.read()
+		if err != nil {
+			return record{}, err
+		}
+		if len(rows) == 0 {
+			return record{}, io.EOF
+		}
+		s.rows = rows
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	s.row++
+
+	cells, rej := s.decode(row)
+	return record{sheet: s.name, row: s.row, cells: cells, reject: rej}, nil
+}
+
+// read decodes the next batch of rows. The Parquet reader panics on some
+// corrupt files, which is turned into an error.
+func (s *parquetSource) read() (rows []any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupt Parquet data: %v", r)
+		}
+	}()
+	return s.pr.ReadByNumber(parquetBatchSize)
+}
+
+// textColumns are the Call fields that are strings but are often exported
+// as integer columns.
+var textColumns = map[string]bool{
+	columnNames[colMsdin]:    true,
+	columnNames[colImeiFrom]: true,
+	columnNames[colImeiTo]:   true,
+}
+
+// decode converts a row to JSON keyed by the column names and decodes it
+// like a JSON Lines record. Timestamp columns become RFC 3339 strings, and
+// integer MSDIN and IMEI columns decimal strings.
+func (s *parquetSource) decode(row any) ([]string, *Rejection) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	fields := make(map[string]any, len(s.columns))
+	for i, col := range s.columns {
+		if i >= v.NumField() {
+			break
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Pointer {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		switch {
+		case col.int96 && f.Kind() == reflect.String:
+			fields[col.name] = types.INT96ToTime(f.String()).UTC().Format(time.RFC3339Nano)
+		case col.unit != 0 && f.CanInt():
+			fields[col.name] = time.Unix(0, f.Int()*int64(col.unit)).UTC().Format(time.RFC3339Nano)
+		case textColumns[col.name] && f.CanInt():
+			fields[col.name] = strconv.FormatInt(f.Int(), 10)
+		case textColumns[col.name] && f.CanUint():
+			fields[col.name] = strconv.FormatUint(f.Uint(), 10)
+		default:
+			fields[col.name] = f.Interface()
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, newRejection("", "", rejectf(ReasonMalformedRecord, "%v", err))
+	}
+	return decodeCall(data)
+}
+
+func (s *parquetSource) Close() error {
+	s.pr.ReadStop()
+	return s.file.Close()
+}
+
+func (s *parquetSource) namedFields() {}
@@ -0,0 +1,116 @@
+package dgraph_imei
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type parquetCall struct {
+	Msdin     string  `parquet:"name=MSDIN, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ImeiFrom  string  `parquet:"name=IMEI_FROM, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Latitude  float64 `parquet:"name=latitude, type=DOUBLE"`
+	Longitude float64 `parquet:"name=longitude, type=DOUBLE"`
+	Duration  *int64  `parquet:"name=duration, type=INT64, repetitiontype=OPTIONAL"`
+	ImeiTo    string  `parquet:"name=IMEI_TO, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CallTime  int64   `parquet:"name=call_time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Operator  string  `parquet:"name=operator, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// openTestParquet writes the rows to a Parquet file with the schema of
+// schema and opens a source on it.
+func openTestParquet[T any](t *testing.T, schema *T, rows []T) *parquetSource {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "calls.parquet")
+	fw, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw, err := writer.NewParquetWriter(fw, schema, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		t.Fatal(err)
+	}
+	fw.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	// The source reads the opened file, not whatever is at its path now.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	src, err := newParquetSource(file, "calls.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { src.Close() })
+	return src
+}
+
+func TestParquetSource(t *testing.T) {
+	duration := int64(12)
+	negative := int64(-1)
+	src := openTestParquet(t, new(parquetCall), []parquetCall{
+		{"89161234567", "490154203237518", 55.7558, 37.6173, &duration, "356938035643809", 1710572645123, "MTS"},
+		{"79161234568", "352099001761481", 0, 0, nil, "490154203237518", 1710572645000, "MTS"},
+		{"79161234569", "490154203237518", 0, 0, &negative, "356938035643809", 1710572645000, "MTS"},
+	})
+	parser, err := newRowParser(DefaultMappingProfile(), DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, report := readTestRecords(t, src, parser)
+
+	if len(calls) != 1 || report.Accepted != 1 {
+		t.Fatalf("got %d calls, want 1", len(calls))
+	}
+	if c := calls[0]; c.Msdin != "+79161234567" || c.Latitude != 55.7558 || c.Duration != 12 || c.CallTime != "2024-03-16T07:04:05.123Z" {
+		t.Errorf("first call = %+v", *c)
+	}
+	// A null duration is missing, not 0.
+	checkRejections(t, report, "calls.parquet", []Rejection{
+		{Row: 2, Column: "duration", Reason: ReasonEmptyValue},
+		{Row: 3, Column: "duration", Value: "-1", Reason: ReasonNegativeNumber},
+	})
+}
+
+// parquetNumericCall has the MSDIN and IMEIs as integer columns.
+type parquetNumericCall struct {
+	Msdin     int64   `parquet:"name=MSDIN, type=INT64"`
+	ImeiFrom  int64   `parquet:"name=IMEI_FROM, type=INT64"`
+	Latitude  float64 `parquet:"name=latitude, type=DOUBLE"`
+	Longitude float64 `parquet:"name=longitude, type=DOUBLE"`
+	Duration  int64   `parquet:"name=duration, type=INT64"`
+	ImeiTo    int64   `parquet:"name=IMEI_TO, type=INT64"`
+	CallTime  string  `parquet:"name=call_time, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func TestParquetSourceIntegerColumns(t *testing.T) {
+	src := openTestParquet(t, new(parquetNumericCall), []parquetNumericCall{
+		{89161234567, 490154203237518, 55.7558, 37.6173, 12, 356938035643809, "2024-03-16T10:04:05+03:00"},
+	})
+	parser, err := newRowParser(DefaultMappingProfile(), DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, report := readTestRecords(t, src, parser)
+	if len(calls) != 1 || len(report.Rejected) != 0 {
+		t.Fatalf("got %d calls and rejections %+v", len(calls), report.Rejected)
+	}
+	if c := calls[0]; c.Msdin != "+79161234567" || c.ImeiFrom != "490154203237518" || c.ImeiTo != "356938035643809" {
+		t.Errorf("call = %+v", *c)
+	}
+}
@@ -0,0 +1,90 @@
+package dgraph_imei
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ParseCalls parses a file on the server with the mapping profile and MSISDN
+// normalizer of the server. Every rejected row is sent before the calls that
+// follow it, so the stream keeps the order of the file.
+func (s *XlsxServer) ParseCalls(req *ParseCallsRequest, stream XlsxService_ParseCallsServer) error {
+	format := Format(req.GetFormat())
+	if format == "" {
+		var err error
+		if format, err = formatFromName(req.GetFilePath()); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	parser, err := newRowParser(s.mapping, s.msisdn)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.open(stream.Context(), req.GetFilePath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	src, err := openSource(format, req.GetFilePath(), file, parser)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer src.Close()
+
+	// The rejections are dropped from the report once they are sent, so a
+	// file with many bad rows does not pile them up in memory.
+	report := &Report{}
+	rejected := 0
+	sendRejections := func() error {
+		for i := range report.Rejected {
+			if err := stream.Send(&ParseCallsResponse{Item: &ParseCallsResponse_Rejection{Rejection: rowRejection(&report.Rejected[i])}}); err != nil {
+				return err
+			}
+		}
+		rejected += len(report.Rejected)
+		report.Rejected = report.Rejected[:0]
+		return nil
+	}
+	err = readRecords(stream.Context(), src, parser, report, 0, func(call *Call, _ int) error {
+		if err := sendRejections(); err != nil {
+			return err
+		}
+		return stream.Send(&ParseCallsResponse{Item: &ParseCallsResponse_Call{Call: callRecord(call)}})
+	})
+	if err != nil {
+		return err
+	}
+	if err := sendRejections(); err != nil {
+		return err
+	}
+	return stream.Send(&ParseCallsResponse{Item: &ParseCallsResponse_Summary{Summary: &ParseSummary{
+		Accepted: int32(report.Accepted),
+		Rejected: int32(rejected),
+	}}})
+}
+
+func callRecord(c *Call) *CallRecord {
+	return &CallRecord{
+		Msdin:         c.Msdin,
+		MsdinOriginal: c.MsdinOriginal,
+		ImeiFrom:      c.ImeiFrom,
+		ImeiTo:        c.ImeiTo,
+		Latitude:      c.Latitude,
+		Longitude:     c.Longitude,
+		Duration:      c.Duration,
+		CallTime:      c.CallTime,
+		CallKey:       c.Key(),
+	}
+}
+
+func rowRejection(r *Rejection) *RowRejection {
+	return &RowRejection{
+		Sheet:  r.Sheet,
+		Row:    int32(r.Row),
+		Column: r.Column,
+		Value:  r.Value,
+		Reason: string(r.Reason),
+		Detail: r.Detail,
+	}
+}
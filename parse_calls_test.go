@@ -0,0 +1,76 @@
+package dgraph_imei
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseCalls(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "calls.csv"), []byte(csvCalls), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mapping := &MappingProfile{Columns: map[string]ColumnSpec{"MSDIN": {Aliases: []string{"Номер"}}}}
+	client := startTestServer(t, newTestServer(t, root, WithServerMappingProfile(mapping)))
+	stream, err := client.ParseCalls(context.Background(), &ParseCallsRequest{FilePath: "calls.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []*ParseCallsResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, resp)
+	}
+	if len(items) != 4 {
+		t.Fatalf("received %d messages, want 2 calls, a rejection and a summary", len(items))
+	}
+
+	call := items[0].GetCall()
+	if call == nil || call.Msdin != "+79161234567" || call.MsdinOriginal != "89161234567" || call.CallTime != "2024-03-16T10:04:05Z" {
+		t.Errorf("first call = %v", call)
+	}
+	if want := (&Call{Msdin: call.GetMsdin(), ImeiFrom: call.GetImeiFrom(), ImeiTo: call.GetImeiTo(), CallTime: call.GetCallTime(), Duration: call.GetDuration()}).Key(); call.GetCallKey() != want {
+		t.Errorf("call key = %s, want %s", call.GetCallKey(), want)
+	}
+	if items[1].GetCall() == nil {
+		t.Errorf("second message = %v, want a call", items[1])
+	}
+	if rej := items[2].GetRejection(); rej == nil || rej.Row != 5 || rej.Column != "Номер" || rej.Reason != string(ReasonNotDigits) {
+		t.Errorf("rejection = %v", rej)
+	}
+	if sum := items[3].GetSummary(); sum == nil || sum.Accepted != 2 || sum.Rejected != 1 {
+		t.Errorf("summary = %v", sum)
+	}
+}
+
+func TestParseCallsInvalidMapping(t *testing.T) {
+	_, err := NewXlsxServer(t.TempDir(), WithServerMappingProfile(&MappingProfile{TimeZone: "Nowhere/Atlantis"}))
+	if err == nil || !strings.Contains(err.Error(), "invalid mapping profile") {
+		t.Errorf("error = %v, want an invalid mapping profile", err)
+	}
+}
+
+func TestParseCallsUnknownFormat(t *testing.T) {
+	client := startTestServer(t, newTestServer(t, ".", WithExtensions(".exe")))
+	stream, err := client.ParseCalls(context.Background(), &ParseCallsRequest{FilePath: "calls.exe"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("error = %v, want InvalidArgument", err)
+	}
+}
@@ -1,112 +1,224 @@
 package dgraph_imei
 
 import (
-	"bytes"
-	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"strconv"
-
-	"github.com/xuri/excelize/v2"
-	"google.golang.org/grpc"
+	"strings"
+	"time"
 )
 
-func readXLSXFile(filePath, grpcAddr string) ([]*Call, error) {
-	conn, err := grpc.Dial(grpcAddr, grpc.WithInsecure())
+// xlsxSource reads the rows of the sheets selected by the mapping profile,
+// one sheet after another.
+type xlsxSource struct {
+	wb     *xlsxWorkbook
+	sheets []xlsxSheet
+	sheet  int // index in sheets of the sheet being read
+	rows   *xlsxSheetReader
+	row    int
+	// pending is a row read ahead while the empty rows missing before it
+	// are returned.
+	pending *xlsxRow
+}
+
+type xlsxRow struct {
+	num   int
+	cells []string
+}
+
+// newXLSXSource opens the workbook and tells parser whether its serial
+// dates count from 1904.
+func newXLSXSource(r io.ReaderAt, size int64, parser *rowParser) (*xlsxSource, error) {
+	// Only the workbook part and the shared strings table are decoded up
+	// front, and the table is spooled to disk. The worksheets are inflated
+	// from the archive and decoded one row at a time while they are read.
+	wb, err := openXLSXWorkbook(r, size)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		return nil, fmt.Errorf("failed to open XLSX data: %w", err)
 	}
-	defer conn.Close()
-	client := NewXlsxServiceClient(conn)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	parser.callTime.date1904 = wb.date1904
 
-	stream, err := client.GetXlsxData(ctx, &GetXlsxRequest{FilePath: filePath})
+	names := make([]string, len(wb.sheets))
+	for i, sheet := range wb.sheets {
+		names[i] = sheet.name
+	}
+	selected, err := parser.mapping.sheetNames(names)
 	if err != nil {
-		log.Fatalf("could not fetch XLSX data: %v", err)
+		wb.Close()
+		return nil, err
 	}
+	src := &xlsxSource{wb: wb}
+	for _, name := range selected {
+		for _, sheet := range wb.sheets {
+			if sheet.name == name {
+				src.sheets = append(src.sheets, sheet)
+				break
+			}
+		}
+	}
+	return src, nil
+}
 
-	var xlsxData []byte
-
+func (s *xlsxSource) next() (record, error) {
 	for {
-		chunk, err := stream.Recv()
-		if err == io.EOF {
-			break
+		if s.rows == nil {
+			if s.sheet >= len(s.sheets) {
+				return record{}, io.EOF
+			}
+			rows, err := s.wb.openSheet(s.sheets[s.sheet])
+			if err != nil {
+				return record{}, err
+			}
+			s.rows, s.row = rows, 0
+		}
+		if s.pending == nil {
+			num, cells, err := s.rows.nextRow()
+			if err == io.EOF {
+				s.rows.Close()
+				s.rows = nil
+				s.sheet++
+				continue
+			}
+			if err != nil {
+				return record{}, err
+			}
+			if num <= s.row {
+				num = s.row + 1
+			}
+			s.pending = &xlsxRow{num: num, cells: cells}
 		}
-		if err != nil {
-			log.Fatalf("failed to receive a chunk : %v", err)
+		s.row++
+		name := s.sheets[s.sheet].name
+		// Rows missing from the sheet data are returned empty, so row
+		// numbers match the ones shown in spreadsheet applications.
+		if s.pending.num > s.row {
+			return record{sheet: name, row: s.row}, nil
 		}
+		cells := s.pending.cells
+		s.pending = nil
+		return record{sheet: name, row: s.row, cells: cells}, nil
+	}
+}
 
-		xlsxData = append(xlsxData, chunk.Chunk...)
+func (s *xlsxSource) sheetNames() []string {
+	names := make([]string, len(s.sheets))
+	for i, sheet := range s.sheets {
+		names[i] = sheet.name
 	}
+	return names
+}
 
-	f, err := excelize.OpenReader(io.NopCloser(bytes.NewReader(xlsxData)))
-	if err != nil {
-		log.Fatalf("failed to open XLSX data: %v", err)
+func (s *xlsxSource) Close() error {
+	if s.rows != nil {
+		s.rows.Close()
 	}
-	defer f.Close()
+	return s.wb.Close()
+}
+
+// Call fields in the order of columnNames.
+const (
+	colMsdin = iota
+	colImeiFrom
+	colLatitude
+	colLongitude
+	colDuration
+	colImeiTo
+	colCallTime
+)
+
+var columnNames = []string{"MSDIN", "IMEI_FROM", "latitude", "longitude", "duration", "IMEI_TO", "call_time"}
 
-	rows, err := f.GetRows("Sheet1")
+// rowParser holds the settings used to find, validate and normalise rows.
+type rowParser struct {
+	mapping  *MappingProfile
+	msisdn   MSISDNNormalizer
+	callTime callTimeParser
+}
+
+func newRowParser(mapping *MappingProfile, msisdn MSISDNNormalizer) (*rowParser, error) {
+	if mapping == nil {
+		return nil, errors.New("no mapping profile")
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	loc, err := mapping.location()
 	if err != nil {
 		return nil, err
 	}
+	return &rowParser{
+		mapping: mapping,
+		msisdn:  msisdn,
+		callTime: callTimeParser{
+			layouts:  mapping.callTimeLayouts(),
+			location: loc,
+		},
+	}, nil
+}
+
+// parseRow validates a single data row and converts it to a Call. Rows
+// shorter than the header are treated as having empty trailing cells.
+func (p *rowParser) parseRow(l *columnLayout, row []string) (*Call, *Rejection) {
+	cells := l.cells(row)
+
+	var err error
+	reject := func(col int) *Rejection {
+		return newRejection(l.header[col], cells[col], err)
+	}
+	present := func(col int) bool {
+		return cells[col] != "" || !l.optional[col]
+	}
 
-	var data []*Call
-	for _, row := range rows[1:] { // Skip header row
-		call := &Call{}
-		if err := validateStringOfDigits(row[0]); err == nil { // MSDIN
-			call.Msdin = row[0]
-		} else {
-			log.Printf("Invalid MSDIN: %s, %s", row[0], err.Error())
-			continue
-		}
-		if err := validateStringOfDigits(row[1]); err == nil { // IMEI_FROM
-			call.ImeiFrom = row[1]
-		} else {
-			log.Printf("Invalid IMEI_FROM: %s, %s", row[1], err.Error())
-			continue
-		}
-		if lat, err := parseFloat(row[2]); err == nil { // latitude
-			call.Latitude = lat
-		} else {
-			log.Printf("Invalid latitude: %s, %s", row[2], err.Error())
-			continue
-		}
-		if lng, err := parseFloat(row[3]); err == nil { // longitude
-			call.Longitude = lng
-		} else {
-			log.Printf("Invalid longitude: %s, %s", row[3], err.Error())
-			continue
+	call := &Call{MsdinOriginal: cells[colMsdin]}
+	if call.Msdin, err = p.msisdn.Normalize(cells[colMsdin]); err != nil {
+		return nil, reject(colMsdin)
+	}
+	imeiFrom, err := ParseIMEI(cells[colImeiFrom])
+	if err != nil {
+		return nil, reject(colImeiFrom)
+	}
+	call.ImeiFrom = string(imeiFrom)
+	if present(colLatitude) {
+		if call.Latitude, err = parseFloat(cells[colLatitude]); err != nil {
+			return nil, reject(colLatitude)
 		}
-		if d, err := parseUnsignedFloat(row[4]); err == nil { // duration
-			call.Duration = d
-		} else {
-			log.Printf("Invalid duration: %s, %s", row[4], err.Error())
-			continue
+		call.HasLatitude = true
+	}
+	if present(colLongitude) {
+		if call.Longitude, err = parseFloat(cells[colLongitude]); err != nil {
+			return nil, reject(colLongitude)
 		}
-		if err := validateStringOfDigits(row[5]); err == nil { // IMEI_TO
-			call.ImeiTo = row[5]
-		} else {
-			log.Printf("Invalid IMEI_TO: %s, %s", row[5], err.Error())
-			continue
+		call.HasLongitude = true
+	}
+	if present(colDuration) {
+		if call.Duration, err = parseUnsignedFloat(cells[colDuration]); err != nil {
+			return nil, reject(colDuration)
 		}
-		call.CallTime = row[6] // call_time
-
-		data = append(data, call)
+		call.HasDuration = true
 	}
-	return data, nil
+	imeiTo, err := ParseIMEI(cells[colImeiTo])
+	if err != nil {
+		return nil, reject(colImeiTo)
+	}
+	call.ImeiTo = string(imeiTo)
+	t, err := p.callTime.parse(cells[colCallTime])
+	if err != nil {
+		return nil, reject(colCallTime)
+	}
+	call.CallTime = t.Format(time.RFC3339Nano)
+
+	return call, nil
 }
 
-func validateStringOfDigits(str string) error {
-	if len(str) == 0  {
-		return errors.New("The string is empty")
-	}
-	if _, err := strconv.ParseInt(str, 10, 64); err != nil {
-		return err
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
 	}
-	return nil
+	return true
 }
 
 func parseUnsignedFloat(str string) (float64, error) {
@@ -115,11 +227,25 @@ func parseUnsignedFloat(str string) (float64, error) {
 		return 0, err
 	}
 	if fl < 0 {
-		return 0, fmt.Errorf("float number is negative: %v", fl)
+		return 0, rejectf(ReasonNegativeNumber, "float number is negative: %v", fl)
 	}
 	return fl, nil
 }
 
 func parseFloat(str string) (float64, error) {
-	return strconv.ParseFloat(str, 64)
+	if len(str) == 0 {
+		return 0, rejectf(ReasonEmptyValue, "the string is empty")
+	}
+	// Exports using ";" as the delimiter often use "," as the decimal mark.
+	if strings.Count(str, ",") == 1 && !strings.Contains(str, ".") {
+		str = strings.Replace(str, ",", ".", 1)
+	}
+	fl, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, rejectf(ReasonInvalidNumber, "%v", err)
+	}
+	if math.IsNaN(fl) || math.IsInf(fl, 0) {
+		return 0, rejectf(ReasonInvalidNumber, "float number is not finite: %v", fl)
+	}
+	return fl, nil
 }
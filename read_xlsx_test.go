@@ -0,0 +1,318 @@
+package dgraph_imei
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// testWorkbook has two sheets of calls, one of them with a rejected row,
+// a sheet of notes and an empty sheet.
+func testWorkbook(t *testing.T) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	header := []any{"MSDIN", "IMEI_FROM", "latitude", "longitude", "duration", "IMEI_TO", "call_time"}
+	sheets := map[string][][]any{
+		"Calls": {
+			header,
+			{"79161234567", "490154203237518", 55.7558, 37.6173, 12, "356938035643809", "2024-03-16T10:04:05Z"},
+		},
+		"Notes": {{"exported by"}, {"billing"}},
+		"Март 2024": {
+			header,
+			{"79161234568", "352099001761481", 55.7558, 37.6173, 24, "490154203237518", "2024-03-17T10:04:05Z"},
+			{"79161234569", "352099001761481", 55.7558, 37.6173, -1, "490154203237518", "2024-03-17T11:04:05Z"},
+		},
+		"Empty": nil,
+	}
+	f.SetSheetName("Sheet1", "Calls")
+	for _, name := range []string{"Notes", "Март 2024", "Empty"} {
+		if _, err := f.NewSheet(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, rows := range sheets {
+		for i, row := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := f.SetSheetRow(name, cell, &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestXLSXSheetSelection(t *testing.T) {
+	data := testWorkbook(t)
+	tests := []struct {
+		name    string
+		mapping MappingProfile
+		sheets  []SheetReport
+		err     string
+	}{
+		{
+			name: "all",
+			sheets: []SheetReport{
+				{Name: "Calls", Header: true, Accepted: 1},
+				{Name: "Notes", Detail: "no column for field MSDIN"},
+				{Name: "Март 2024", Header: true, Accepted: 1, Rejected: 1},
+				{Name: "Empty", Detail: "no header row 1"},
+			},
+		},
+		{
+			name:    "pattern",
+			mapping: MappingProfile{SheetPattern: "МАРТ *"},
+			sheets:  []SheetReport{{Name: "Март 2024", Header: true, Accepted: 1, Rejected: 1}},
+		},
+		{
+			name:    "index",
+			mapping: MappingProfile{SheetIndex: 1},
+			sheets:  []SheetReport{{Name: "Calls", Header: true, Accepted: 1}},
+		},
+		{
+			name:    "no header",
+			mapping: MappingProfile{Sheet: "Notes"},
+			err:     "no sheet has a call header: sheet Notes: no column for field MSDIN",
+		},
+		{
+			name:    "no match",
+			mapping: MappingProfile{SheetPattern: "Calls 20*"},
+			err:     `no sheet matches "Calls 20*"`,
+		},
+	}
+	for _, tt := range tests {
+		parser, err := newRowParser(&tt.mapping, DefaultMSISDNNormalizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := &Report{}
+		src, err := newXLSXSource(bytes.NewReader(data), int64(len(data)), parser)
+		if err == nil {
+			err = readRecords(context.Background(), src, parser, report, 0, func(*Call, int) error { return nil })
+			src.Close()
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(report.Sheets) != len(tt.sheets) {
+			t.Fatalf("%s: sheets = %+v, want %+v", tt.name, report.Sheets, tt.sheets)
+		}
+		for i, want := range tt.sheets {
+			if got := report.Sheets[i]; got != want {
+				t.Errorf("%s: sheet %d = %+v, want %+v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+// TestXLSXShortRows checks that rows with fewer cells than the header are
+// rejected for their first missing field instead of being indexed past
+// their end.
+func TestXLSXShortRows(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	rows := [][]any{
+		{"MSDIN", "IMEI_FROM", "latitude", "longitude", "duration", "IMEI_TO", "call_time"},
+		{"79161234567", "490154203237518", 55.7558},
+		{"79161234568"},
+		{"79161234569", "490154203237518", 55.7558, 37.6173, 12, "356938035643809", "2024-03-16T10:04:05Z"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := newRowParser(DefaultMappingProfile(), DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := newXLSXSource(bytes.NewReader(buf.Bytes()), int64(buf.Len()), parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	calls, report := readTestRecords(t, src, parser)
+
+	if len(calls) != 1 || report.Accepted != 1 {
+		t.Fatalf("got %d calls, want 1", len(calls))
+	}
+	checkRejections(t, report, "Sheet1", []Rejection{
+		{Row: 2, Column: "longitude", Reason: ReasonEmptyValue},
+		{Row: 3, Column: "IMEI_FROM", Reason: ReasonEmptyValue},
+	})
+}
+
+// TestXLSXSourceMatchesExcelize checks that the streaming reader returns the
+// raw cell values excelize returns for the same workbooks.
+func TestXLSXSourceMatchesExcelize(t *testing.T) {
+	files := map[string][]byte{"generated": testWorkbook(t)}
+	if data, err := os.ReadFile("test_file.xlsx"); err == nil {
+		files["test_file.xlsx"] = data
+	}
+	for name, data := range files {
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []record
+		for _, sheet := range f.GetSheetList() {
+			rows, err := f.Rows(sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; rows.Next(); i++ {
+				cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, record{sheet: sheet, row: i, cells: cells})
+			}
+			rows.Close()
+		}
+		f.Close()
+
+		parser, err := newRowParser(&MappingProfile{}, DefaultMSISDNNormalizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := newXLSXSource(bytes.NewReader(data), int64(len(data)), parser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := readAll(t, src)
+		if !reflect.DeepEqual(normalizeRecords(got), normalizeRecords(want)) {
+			t.Errorf("%s: records =\n%v\nwant\n%v", name, got, want)
+		}
+	}
+}
+
+func TestXLSXSourceCells(t *testing.T) {
+	data := zipParts(t, map[string]string{
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="book/main.xml"/>
+</Relationships>`,
+		"book/_rels/main.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="sheets/one.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="/book/strings.xml"/>
+</Relationships>`,
+		"book/main.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<workbookPr date1904="1"/>
+<sheets><sheet name="Calls" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`,
+		"book/strings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>MSDIN</t></si>
+<si><r><t>Call</t></r><r><rPr><b/></rPr><t xml:space="preserve"> time</t></r><rPh><t>ignored</t></rPh></si>
+<si><t>tab_x0009_here _x005F_x0041_</t></si>
+</sst>`,
+		"book/sheets/one.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="3"><c t="inlineStr"><is><t>inline</t></is></c><c r="B3"/><c r="D3"><f>1+1</f></c><c><v>4.5</v></c></row>
+<row r="4"><c r="AA4" t="s"><v>2</v></c></row>
+<row><c t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+	})
+	parser, err := newRowParser(&MappingProfile{}, DefaultMSISDNNormalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := newXLSXSource(bytes.NewReader(data), int64(len(data)), parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parser.callTime.date1904 {
+		t.Error("date1904 was not read from the workbook properties")
+	}
+	spooled := src.wb.strings.file.Name()
+	got := readAll(t, src)
+	if _, err := os.Stat(spooled); !os.IsNotExist(err) {
+		t.Errorf("the spooled shared strings were not removed: %v", err)
+	}
+	sparse := make([]string, 27)
+	sparse[26] = "tab\there _x0041_"
+	want := []record{
+		{sheet: "Calls", row: 1, cells: []string{"MSDIN", "", "Call time"}},
+		{sheet: "Calls", row: 2},
+		{sheet: "Calls", row: 3, cells: []string{"inline", "", "", "", "4.5"}},
+		{sheet: "Calls", row: 4, cells: sparse},
+		{sheet: "Calls", row: 5, cells: []string{"1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func zipParts(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, src *xlsxSource) []record {
+	t.Helper()
+	defer src.Close()
+	var records []record
+	for {
+		rec, err := src.next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// normalizeRecords treats nil and empty cell slices alike.
+func normalizeRecords(records []record) []record {
+	out := make([]record, len(records))
+	for i, rec := range records {
+		if len(rec.cells) == 0 {
+			rec.cells = nil
+		}
+		out[i] = rec
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package dgraph_imei
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RejectReason tells why a spreadsheet row was not ingested.
+type RejectReason string
+
+const (
+	ReasonEmptyValue      RejectReason = "empty_value"
+	ReasonNotDigits       RejectReason = "not_digits"
+	ReasonInvalidIMEI     RejectReason = "invalid_imei"
+	ReasonBadChecksum     RejectReason = "bad_checksum"
+	ReasonInvalidMSISDN   RejectReason = "invalid_msisdn"
+	ReasonInvalidNumber   RejectReason = "invalid_number"
+	ReasonNegativeNumber  RejectReason = "negative_number"
+	ReasonInvalidDatetime RejectReason = "invalid_datetime"
+	ReasonInvalidValue    RejectReason = "invalid_value"
+	ReasonMalformedRecord RejectReason = "malformed_record"
+)
+
+// Rejection describes a row that was not ingested and the first cell that
+// made it invalid. Row is the 1-based row number as shown by spreadsheet
+// applications.
+type Rejection struct {
+	Sheet  string       `json:"sheet"`
+	Row    int          `json:"row"`
+	Column string       `json:"column"`
+	Value  string       `json:"value"`
+	Reason RejectReason `json:"reason"`
+	Detail string       `json:"detail"`
+}
+
+// Report summarises the ingestion of a file. When an interrupted import is
+// resumed, it covers only the records read by the resumed run.
+type Report struct {
+	// ImportID is the uid of the import node recording the ingestion.
+	ImportID string `json:"import_id"`
+	// Skipped is the number of records an earlier run of the import had
+	// already committed and that were not read again.
+	Skipped  int           `json:"skipped,omitempty"`
+	Accepted int           `json:"accepted"`
+	Rejected []Rejection   `json:"rejected"`
+	Sheets   []SheetReport `json:"sheets"`
+}
+
+// SheetReport summarises the ingestion of one sheet of a workbook. Other
+// formats are reported as a single sheet named after the file.
+type SheetReport struct {
+	Name string `json:"name"`
+	// Header tells whether a call header was recognised. Sheets without
+	// one are skipped and Detail says why.
+	Header   bool   `json:"header"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// sheet returns the index in Sheets of the named sheet, adding it if it
+// is not there yet.
+func (r *Report) sheet(name string) int {
+	for i := range r.Sheets {
+		if r.Sheets[i].Name == name {
+			return i
+		}
+	}
+	r.Sheets = append(r.Sheets, SheetReport{Name: name})
+	return len(r.Sheets) - 1
+}
+
+// WriteJSON writes the whole report as a JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the rejected rows as CSV with a header line. Cells that
+// spreadsheet applications would run as formulas are prefixed with "'".
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"sheet", "row", "column", "value", "reason", "detail"}); err != nil {
+		return err
+	}
+	for _, rej := range r.Rejected {
+		record := []string{csvText(rej.Sheet), strconv.Itoa(rej.Row), csvText(rej.Column), csvText(rej.Value), string(rej.Reason), csvText(rej.Detail)}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText neutralises a cell read from an ingested file, which may start
+// like a formula.
+func csvText(s string) string {
+	if s != "" && strings.IndexByte("=+-@\t\r", s[0]) >= 0 {
+		return "'" + s
+	}
+	return s
+}
+
+// reasonError is a validation error that knows its RejectReason.
+type reasonError struct {
+	reason RejectReason
+	msg    string
+}
+
+func (e *reasonError) Error() string {
+	return e.msg
+}
+
+func rejectf(reason RejectReason, format string, args ...any) error {
+	return &reasonError{reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+// newRejection describes an invalid cell. Errors that do not carry a
+// reason are reported as ReasonInvalidValue.
+func newRejection(column, value string, err error) *Rejection {
+	rej := &Rejection{Column: column, Value: value, Reason: ReasonInvalidValue, Detail: err.Error()}
+	var re *reasonError
+	if errors.As(err, &re) {
+		rej.Reason = re.reason
+	}
+	return rej
+}
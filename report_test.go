@@ -0,0 +1,103 @@
+package dgraph_imei
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testReport() *Report {
+	return &Report{
+		ImportID: "0x2a",
+		Accepted: 3,
+		Rejected: []Rejection{
+			{Sheet: "Calls", Row: 4, Column: "MSDIN", Value: "7916,123;45", Reason: ReasonNotDigits, Detail: `MSISDN contains a non-digit character ','`},
+			{Sheet: "Март 2024", Row: 7, Column: "call_time", Value: "16.03.2024\n10:04", Reason: ReasonInvalidDatetime, Detail: "cannot parse \"16.03.2024\n10:04\""},
+		},
+		Sheets: []SheetReport{
+			{Name: "Calls", Header: true, Accepted: 3, Rejected: 1},
+			{Name: "Март 2024", Header: true, Rejected: 1},
+			{Name: "Notes", Detail: "no column for field MSDIN"},
+		},
+	}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	report := testReport()
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, report) {
+		t.Errorf("decoded report = %+v, want %+v", got, *report)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["skipped"]; ok {
+		t.Error("skipped is written although no record was skipped")
+	}
+}
+
+func TestReportWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "sheet,row,column,value,reason,detail\n" +
+		"Calls,4,MSDIN,\"7916,123;45\",not_digits,\"MSISDN contains a non-digit character ','\"\n" +
+		"Март 2024,7,call_time,\"16.03.2024\n10:04\",invalid_datetime,\"cannot parse \"\"16.03.2024\n10:04\"\"\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2][3] != "16.03.2024\n10:04" || records[2][5] != `cannot parse "16.03.2024`+"\n"+`10:04"` {
+		t.Errorf("records read back = %q", records)
+	}
+}
+
+func TestReportWriteCSVFormulas(t *testing.T) {
+	report := &Report{Rejected: []Rejection{
+		{Sheet: "=Calls", Row: 2, Column: "duration", Value: "-1", Reason: ReasonNegativeNumber, Detail: "@SUM(A1)"},
+		{Sheet: "Calls", Row: 3, Column: "MSDIN", Value: "=HYPERLINK(\"http://x\")", Reason: ReasonNotDigits},
+		{Sheet: "Calls", Row: 4, Column: "MSDIN", Value: "+7916", Reason: ReasonNotDigits, Detail: "a+b"},
+	}}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"sheet", "row", "column", "value", "reason", "detail"},
+		{"'=Calls", "2", "duration", "'-1", "negative_number", "'@SUM(A1)"},
+		{"Calls", "3", "MSDIN", "'=HYPERLINK(\"http://x\")", "not_digits", ""},
+		{"Calls", "4", "MSDIN", "'+7916", "not_digits", "a+b"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+func TestReportWriteCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&Report{}).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "sheet,row,column,value,reason,detail\n"; got != want {
+		t.Errorf("CSV = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package dgraph_imei
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+)
+
+const (
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = 50 * time.Millisecond
+	maxBackoff            = 5 * time.Second
+)
+
+// retryPolicy retries transactions that Dgraph aborted because they
+// conflicted with another transaction, waiting exponentially longer before
+// each retry. Other errors are returned at once.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{maxRetries: defaultMaxRetries, initialBackoff: defaultInitialBackoff}
+}
+
+// do runs txn until it succeeds, fails with an error other than
+// dgo.ErrAborted or has been retried maxRetries times.
+func (p retryPolicy) do(ctx context.Context, txn func() error) error {
+	backoff := max(p.initialBackoff, time.Millisecond)
+	for retries := 0; ; retries++ {
+		err := txn()
+		if err == nil || !errors.Is(err, dgo.ErrAborted) || retries >= p.maxRetries {
+			return err
+		}
+		// The jitter keeps workers that conflicted with each other from
+		// retrying in lockstep.
+		delay := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
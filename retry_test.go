@@ -0,0 +1,70 @@
+package dgraph_imei
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := retryPolicy{maxRetries: 3, initialBackoff: time.Millisecond}
+	errOther := errors.New("schema error")
+	tests := []struct {
+		name     string
+		failures []error
+		attempts int
+		err      error
+	}{
+		{"success", nil, 1, nil},
+		{"conflict", []error{dgo.ErrAborted, fmt.Errorf("upsert: %w", dgo.ErrAborted)}, 3, nil},
+		{"other error", []error{errOther}, 1, errOther},
+		{"too many conflicts", []error{dgo.ErrAborted, dgo.ErrAborted, dgo.ErrAborted, dgo.ErrAborted, dgo.ErrAborted}, 4, dgo.ErrAborted},
+	}
+	for _, tt := range tests {
+		attempts := 0
+		err := p.do(context.Background(), func() error {
+			attempts++
+			if attempts <= len(tt.failures) {
+				return tt.failures[attempts-1]
+			}
+			return nil
+		})
+		if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+		if attempts != tt.attempts {
+			t.Errorf("%s: %d attempts, want %d", tt.name, attempts, tt.attempts)
+		}
+	}
+}
+
+func TestRetryPolicyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := retryPolicy{maxRetries: 10, initialBackoff: time.Hour}
+	err := p.do(ctx, func() error {
+		cancel()
+		return dgo.ErrAborted
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestShardOf(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		msdin := fmt.Sprintf("+7916123%04d", i)
+		shard := shardOf(msdin, 4)
+		if shard != shardOf(msdin, 4) || shard < 0 || shard >= 4 {
+			t.Fatalf("shardOf(%s) = %d", msdin, shard)
+		}
+		seen[shard] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("100 accounts use %d of 4 shards", len(seen))
+	}
+}
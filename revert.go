@@ -0,0 +1,315 @@
+package dgraph_imei
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+	"github.com/dgraph-io/dgo/v230/protos/api"
+)
+
+// revertBatchSize is the number of calls of an import reverted in one
+// transaction.
+const revertBatchSize = 500
+
+// RevertReport summarises the rollback of an import.
+type RevertReport struct {
+	// Deleted counts the calls no other import contained.
+	Deleted int `json:"deleted"`
+	// Unlinked counts the calls other imports contained too. They are kept
+	// and only lose their link to the reverted import.
+	Unlinked int `json:"unlinked"`
+	// PrunedEdges counts the device and account edges that no remaining
+	// call supported.
+	PrunedEdges int `json:"pruned_edges"`
+}
+
+// RevertImport deletes the calls of an import that no other import
+// contained, together with the imeis, imeis_to, incoming_msdin and
+// outgoing_msdin edges no remaining call supports. importID is the uid
+// reported in Report.ImportID. The calls are reverted in batches, so an
+// interrupted revert can be completed by calling RevertImport again. Imports
+// that are still running are refused; an import whose process died is
+// resumed by ingesting its file again, and can be reverted once it ends.
+func (c *FileClient) RevertImport(ctx context.Context, importID string) (*RevertReport, error) {
+	imp, err := uidNode(importID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import id: %w", err)
+	}
+	if err := setImportStatus(ctx, c.dgraphClient, importID, imp, ImportReverting, "", ImportRunning); err != nil {
+		return nil, err
+	}
+
+	report := &RevertReport{}
+	for {
+		n, err := revertCalls(ctx, c.dgraphClient, importID, imp, report)
+		if err != nil {
+			return report, fmt.Errorf("failed to revert import %s: %w", importID, err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return report, setImportStatus(ctx, c.dgraphClient, importID, imp, ImportReverted, "import_reverted_at", "")
+}
+
+// setImportStatus sets the status of an existing import node and, when
+// timePredicate is not empty, stores the current time in it. The node is
+// left alone if its status is refused, unless refused is empty. importID
+// must have been checked by uidNode.
+func setImportStatus(ctx context.Context, client *dgo.Dgraph, importID string, imp nquadNode, status, timePredicate, refused string) error {
+	txn := client.NewTxn()
+	defer txn.Discard(ctx)
+
+	nq := &nquadBuilder{}
+	nq.literal(imp, "import_status", status)
+	if timePredicate != "" {
+		nq.typed(imp, timePredicate, time.Now().UTC().Format(time.RFC3339Nano), xsDateTime)
+	}
+	query := fmt.Sprintf("{\n\timport(func: uid(%s)) @filter(type(import)) {\n\t\timp as uid\n\t\timport_status\n\t}\n}", importID)
+	cond := "@if(eq(len(imp), 1))"
+	vars := map[string]string(nil)
+	if refused != "" {
+		query = fmt.Sprintf(`query q($refused: string) {
+			import(func: uid(%s)) @filter(type(import)) {
+				imp as uid
+				import_status
+			}
+			refused as var(func: uid(imp)) @filter(eq(import_status, $refused))
+		}`, importID)
+		cond = "@if(eq(len(imp), 1) AND eq(len(refused), 0))"
+		vars = map[string]string{"$refused": refused}
+	}
+	req := &api.Request{
+		Query:     query,
+		Vars:      vars,
+		Mutations: []*api.Mutation{{SetNquads: nq.bytes(), Cond: cond}},
+		CommitNow: true,
+	}
+	resp, err := txn.Do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to set the status of import %s: %w", importID, err)
+	}
+	var result struct {
+		Import []struct {
+			Status string `json:"import_status"`
+		} `json:"import"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return err
+	}
+	if len(result.Import) == 0 {
+		return fmt.Errorf("import %s not found", importID)
+	}
+	if refused != "" && result.Import[0].Status == refused {
+		return fmt.Errorf("import %s is %s", importID, refused)
+	}
+	return nil
+}
+
+// uidRef decodes a uid edge, which Dgraph returns as an object or, for
+// list predicates, as an array.
+type uidRef struct {
+	UID string
+}
+
+func (r *uidRef) UnmarshalJSON(data []byte) error {
+	var node struct {
+		UID string `json:"uid"`
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var nodes []struct {
+			UID string `json:"uid"`
+		}
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return err
+		}
+		if len(nodes) > 0 {
+			r.UID = nodes[0].UID
+		}
+		return nil
+	}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	r.UID = node.UID
+	return nil
+}
+
+// revertCalls reverts the next batch of calls of the import in a single
+// transaction and returns the number of calls it processed.
+func revertCalls(ctx context.Context, client *dgo.Dgraph, importID string, imp nquadNode, report *RevertReport) (int, error) {
+	txn := client.NewTxn()
+	defer txn.Discard(ctx)
+
+	resp, err := txn.Query(ctx, fmt.Sprintf(`{
+		import(func: uid(%s)) {
+			calls: ~imported_by (first: %d) {
+				uid
+				imports: count(imported_by)
+				IMEI_FROM_UID { uid }
+				IMEI_TO_UID { uid }
+				MSDIN_UID { uid }
+			}
+		}
+	}`, importID, revertBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		Import []struct {
+			Calls []struct {
+				UID      string `json:"uid"`
+				Imports  int    `json:"imports"`
+				ImeiFrom uidRef `json:"IMEI_FROM_UID"`
+				ImeiTo   uidRef `json:"IMEI_TO_UID"`
+				Msdin    uidRef `json:"MSDIN_UID"`
+			} `json:"calls"`
+		} `json:"import"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return 0, err
+	}
+	if len(result.Import) == 0 || len(result.Import[0].Calls) == 0 {
+		return 0, nil
+	}
+	calls := result.Import[0].Calls
+
+	del := &nquadBuilder{}
+	var deleted []string
+	var edges []impliedEdge
+	seen := make(map[impliedEdge]bool)
+	for _, call := range calls {
+		node, err := uidNode(call.UID)
+		if err != nil {
+			return 0, err
+		}
+		if call.Imports > 1 {
+			del.edge(node, "imported_by", imp)
+			report.Unlinked++
+			continue
+		}
+		// The link is removed explicitly in case the call has no type,
+		// which would leave it in the import after the wildcard deletion.
+		del.wildcardNode(node)
+		del.edge(node, "imported_by", imp)
+		deleted = append(deleted, call.UID)
+		report.Deleted++
+		if call.ImeiFrom.UID == "" || call.ImeiTo.UID == "" || call.Msdin.UID == "" {
+			continue
+		}
+		implied, err := impliedEdges(call.ImeiFrom.UID, call.ImeiTo.UID, call.Msdin.UID)
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range implied {
+			if !seen[e] {
+				seen[e] = true
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	unsupported, err := unsupportedEdges(ctx, txn, deleted, edges)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range unsupported {
+		subject, err := uidNode(e.subject)
+		if err != nil {
+			return 0, err
+		}
+		object, err := uidNode(e.object)
+		if err != nil {
+			return 0, err
+		}
+		del.edge(subject, e.predicate, object)
+	}
+	report.PrunedEdges += len(unsupported)
+
+	if _, err := txn.Mutate(ctx, &api.Mutation{DelNquads: del.bytes(), CommitNow: true}); err != nil {
+		return 0, err
+	}
+	return len(calls), nil
+}
+
+// impliedEdge is an edge between devices and accounts that calls imply.
+type impliedEdge struct {
+	subject, predicate, object string
+	// reverse is the call predicate pointing at the subject and forward
+	// the one pointing at the object in a call that implies the edge.
+	reverse, forward string
+	// symmetric edges are also implied by calls with the predicates
+	// swapped, like imeis_to between two devices.
+	symmetric bool
+}
+
+// impliedEdges returns the edges a call between the given devices made
+// from the given account implies, as written by buildUpsertRequest. The
+// uids are checked, since the edges are written into a query.
+func impliedEdges(from, to, acc string) ([]impliedEdge, error) {
+	for _, uid := range []string{from, to, acc} {
+		if _, err := uidNode(uid); err != nil {
+			return nil, err
+		}
+	}
+	return []impliedEdge{
+		{from, "imeis_to", to, "IMEI_FROM_UID", "IMEI_TO_UID", true},
+		{to, "imeis_to", from, "IMEI_FROM_UID", "IMEI_TO_UID", true},
+		{acc, "imeis", from, "MSDIN_UID", "IMEI_FROM_UID", false},
+		{acc, "imeis_to", to, "MSDIN_UID", "IMEI_TO_UID", false},
+		{from, "incoming_msdin", acc, "IMEI_FROM_UID", "MSDIN_UID", false},
+		{to, "outgoing_msdin", acc, "IMEI_TO_UID", "MSDIN_UID", false},
+	}, nil
+}
+
+// unsupportedEdges returns the edges that no call other than the deleted
+// ones implies.
+func unsupportedEdges(ctx context.Context, txn *dgo.Txn, deleted []string, edges []impliedEdge) ([]impliedEdge, error) {
+	if len(edges) == 0 {
+		return nil, nil
+	}
+	resp, err := txn.Query(ctx, buildSupportQuery(deleted, edges))
+	if err != nil {
+		return nil, err
+	}
+	var result map[string][]map[string]int
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, err
+	}
+
+	var unsupported []impliedEdge
+	for i, e := range edges {
+		support := 0
+		for _, counts := range result[fmt.Sprintf("e%d", i)] {
+			for _, n := range counts {
+				support += n
+			}
+		}
+		if support == 0 {
+			unsupported = append(unsupported, e)
+		}
+	}
+	return unsupported, nil
+}
+
+// buildSupportQuery counts, for every edge, the calls other than the
+// deleted ones that imply it. The uids come from Dgraph responses; the
+// deleted calls are checked by revertCalls and the edges by impliedEdges.
+func buildSupportQuery(deleted []string, edges []impliedEdge) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\n\tdeleted as var(func: uid(%s))\n", strings.Join(deleted, ", "))
+	for i, e := range edges {
+		fmt.Fprintf(&b, "\te%d(func: uid(%s)) {\n", i, e.subject)
+		fmt.Fprintf(&b, "\t\tc0: count(~%s @filter(uid_in(%s, %s) AND NOT uid(deleted)))\n", e.reverse, e.forward, e.object)
+		if e.symmetric {
+			fmt.Fprintf(&b, "\t\tc1: count(~%s @filter(uid_in(%s, %s) AND NOT uid(deleted)))\n", e.forward, e.reverse, e.object)
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
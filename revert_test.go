@@ -0,0 +1,44 @@
+package dgraph_imei
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuildSupportQuery(t *testing.T) {
+	edges, err := impliedEdges("0x1", "0x2", "0x3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := buildSupportQuery([]string{"0x10", "0x11"}, edges)
+
+	if !strings.Contains(q, "deleted as var(func: uid(0x10, 0x11))") {
+		t.Errorf("query does not bind the deleted calls:\n%s", q)
+	}
+	want := []string{
+		"e0(func: uid(0x1)) {\n\t\tc0: count(~IMEI_FROM_UID @filter(uid_in(IMEI_TO_UID, 0x2) AND NOT uid(deleted)))\n\t\tc1: count(~IMEI_TO_UID @filter(uid_in(IMEI_FROM_UID, 0x2) AND NOT uid(deleted)))\n\t}",
+		"e2(func: uid(0x3)) {\n\t\tc0: count(~MSDIN_UID @filter(uid_in(IMEI_FROM_UID, 0x1) AND NOT uid(deleted)))\n\t}",
+		"e5(func: uid(0x2)) {\n\t\tc0: count(~IMEI_TO_UID @filter(uid_in(MSDIN_UID, 0x3) AND NOT uid(deleted)))\n\t}",
+	}
+	for _, w := range want {
+		if !strings.Contains(q, w) {
+			t.Errorf("query lacks block\n%s\nin:\n%s", w, q)
+		}
+	}
+}
+
+func TestImpliedEdgesInvalidUID(t *testing.T) {
+	if _, err := impliedEdges("0x1", "0x2) { uid } }", "0x3"); err == nil {
+		t.Error("impliedEdges accepted an invalid uid")
+	}
+}
+
+func TestUIDRef(t *testing.T) {
+	for _, data := range []string{`{"uid":"0x1"}`, `[{"uid":"0x1"}]`} {
+		var r uidRef
+		if err := json.Unmarshal([]byte(data), &r); err != nil || r.UID != "0x1" {
+			t.Errorf("decoding %s: %+v, %v", data, r, err)
+		}
+	}
+}
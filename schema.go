@@ -0,0 +1,312 @@
+package dgraph_imei
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgo/v230"
+	"github.com/dgraph-io/dgo/v230/protos/api"
+)
+
+const callSchema = `
+	call_time: datetime @index(day) .
+	latitude: float .
+	longitude: float .
+	duration: float .
+	IMEI_FROM_UID: uid .
+	IMEI_TO_UID: uid .
+	MSDIN_UID: uid .
+
+	type call {
+		call_time
+		latitude
+		longitude
+		duration
+		IMEI_FROM_UID
+		IMEI_TO_UID
+		MSDIN_UID
+	}
+`
+
+const deviceSchema = `
+	IMEI: string @index(exact) .
+	imeis_to: [uid] .
+	incoming_msdin: [uid] .
+	outgoing_msdin: [uid] .
+
+	type device {
+		IMEI
+		imeis_to
+		incoming_msdin
+		outgoing_msdin
+	}
+`
+
+const accountSchema = `
+	MSDIN: string @index(exact) .
+	imeis: [uid] .
+	imeis_to: [uid] .
+
+	type account {
+		MSDIN
+		imeis
+		imeis_to
+	}
+`
+
+// deviceIdentitySchema stores the parts of the IMEI on the device node.
+const deviceIdentitySchema = `
+	TAC: string @index(exact) .
+	serial_number: string .
+
+	type device {
+		IMEI
+		TAC
+		serial_number
+		imeis_to
+		incoming_msdin
+		outgoing_msdin
+	}
+`
+
+// accountOriginalSchema keeps the MSDIN spellings found in the source files
+// for accounts whose MSDIN is stored in E.164 format.
+const accountOriginalSchema = `
+	MSDIN_original: [string] .
+
+	type account {
+		MSDIN
+		MSDIN_original
+		imeis
+		imeis_to
+	}
+`
+
+// callKeySchema identifies calls by Call.Key. The @upsert directive makes
+// concurrent imports of the same call conflict instead of both creating it.
+const callKeySchema = `
+	call_key: string @index(exact) @upsert .
+
+	type call {
+		call_key
+		call_time
+		latitude
+		longitude
+		duration
+		IMEI_FROM_UID
+		IMEI_TO_UID
+		MSDIN_UID
+	}
+`
+
+// importSchema records every import in an import node. Calls link to the
+// imports that contained them; the reverse edge finds the calls of an
+// import.
+const importSchema = `
+	import_file: string .
+	import_hash: string @index(exact) @upsert .
+	import_format: string .
+	import_status: string @index(exact) .
+	import_started_at: datetime .
+	import_finished_at: datetime .
+	import_checkpoint: int .
+	import_accepted: int .
+	import_rejected: int .
+	import_error: string .
+	imported_by: [uid] @reverse .
+
+	type import {
+		import_file
+		import_hash
+		import_format
+		import_status
+		import_started_at
+		import_finished_at
+		import_checkpoint
+		import_accepted
+		import_rejected
+		import_error
+	}
+
+	type call {
+		call_key
+		call_time
+		latitude
+		longitude
+		duration
+		IMEI_FROM_UID
+		IMEI_TO_UID
+		MSDIN_UID
+		imported_by
+	}
+`
+
+// revertSchema lets RevertImport find the calls of devices and accounts and
+// records when an import was reverted.
+const revertSchema = `
+	IMEI_FROM_UID: uid @reverse .
+	IMEI_TO_UID: uid @reverse .
+	MSDIN_UID: uid @reverse .
+	import_reverted_at: datetime .
+
+	type import {
+		import_file
+		import_hash
+		import_format
+		import_status
+		import_started_at
+		import_finished_at
+		import_reverted_at
+		import_checkpoint
+		import_accepted
+		import_rejected
+		import_error
+	}
+`
+
+// upsertIdentitySchema makes transactions that both create the device or
+// account with the same IMEI or MSDIN conflict, so one of them is retried
+// and finds the node the other created instead of adding a duplicate.
+const upsertIdentitySchema = `
+	IMEI: string @index(exact) @upsert .
+	MSDIN: string @index(exact) @upsert .
+`
+
+// schemaMetaSchema describes the node that records which schema version
+// has been applied to the graph.
+const schemaMetaSchema = `
+	schema_version: int .
+
+	type schema_meta {
+		schema_version
+	}
+`
+
+// schemaMigration is a forward step of the graph schema. Migrations are
+// applied in order, each one exactly once per graph.
+type schemaMigration struct {
+	version int
+	schema  string
+}
+
+// schemaMigrations must be kept sorted by version. New schema changes are
+// appended as a new migration, never edited into an applied one.
+var schemaMigrations = []schemaMigration{
+	{version: 1, schema: deviceSchema + accountSchema + callSchema},
+	{version: 2, schema: deviceIdentitySchema},
+	{version: 3, schema: accountOriginalSchema},
+	{version: 4, schema: callKeySchema},
+	{version: 5, schema: importSchema},
+	{version: 6, schema: revertSchema},
+	{version: 7, schema: upsertIdentitySchema},
+}
+
+// currentSchemaVersion is the version the client expects the graph to have.
+func currentSchemaVersion() int {
+	return schemaMigrations[len(schemaMigrations)-1].version
+}
+
+func alterSchema(ctx context.Context, client *dgo.Dgraph, schema string) error {
+	op := &api.Operation{Schema: schema}
+	if err := client.Alter(ctx, op); err != nil {
+		return fmt.Errorf("failed to alter schema: %w", err)
+	}
+	return nil
+}
+
+// schemaTimeout bounds the schema check and migration of NewClient.
+// Migrations rebuild indexes, so it is longer than the login timeout.
+const schemaTimeout = 5 * time.Minute
+
+// ensureSchema brings the graph schema up to date by applying every
+// migration newer than the version stored in the graph. The schema is only
+// altered when a migration is pending, so clients of an up to date graph
+// do not need the permission to alter it.
+func ensureSchema(ctx context.Context, client *dgo.Dgraph) error {
+	// The version query works before the schema_meta type exists: it
+	// finds no node and reports version 0.
+	applied, err := appliedSchemaVersion(ctx, client)
+	if err != nil {
+		return err
+	}
+	if applied > currentSchemaVersion() {
+		return fmt.Errorf("graph schema version %d is newer than supported version %d", applied, currentSchemaVersion())
+	}
+	if applied == currentSchemaVersion() {
+		return nil
+	}
+	if err := alterSchema(ctx, client, schemaMetaSchema); err != nil {
+		return err
+	}
+	for _, m := range schemaMigrations {
+		if m.version <= applied {
+			continue
+		}
+		if err := alterSchema(ctx, client, m.schema); err != nil {
+			return fmt.Errorf("schema migration %d: %w", m.version, err)
+		}
+		if err := storeSchemaVersion(ctx, client, m.version); err != nil {
+			return err
+		}
+		log.Printf("Applied schema migration %d", m.version)
+	}
+	return nil
+}
+
+// appliedSchemaVersion returns the schema version stored in the graph, or 0
+// for a graph that has never been migrated.
+func appliedSchemaVersion(ctx context.Context, client *dgo.Dgraph) (int, error) {
+	txn := client.NewReadOnlyTxn()
+	defer txn.Discard(ctx)
+
+	resp, err := txn.Query(ctx, `{
+		meta(func: type(schema_meta)) {
+			schema_version
+		}
+	}`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	var result struct {
+		Meta []struct {
+			SchemaVersion int `json:"schema_version"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for _, m := range result.Meta {
+		if m.SchemaVersion > version {
+			version = m.SchemaVersion
+		}
+	}
+	return version, nil
+}
+
+func storeSchemaVersion(ctx context.Context, client *dgo.Dgraph, version int) error {
+	txn := client.NewTxn()
+	defer txn.Discard(ctx)
+
+	nq := &nquadBuilder{}
+	meta := uidVar("meta")
+	nq.typed(meta, "schema_version", strconv.Itoa(version), xsInt)
+	nq.literal(meta, "dgraph.type", "schema_meta")
+
+	mu := &api.Mutation{SetNquads: nq.bytes()}
+	req := &api.Request{
+		Query:     `query { meta as var(func: type(schema_meta)) }`,
+		Mutations: []*api.Mutation{mu},
+		CommitNow: true,
+	}
+	if _, err := txn.Do(ctx, req); err != nil {
+		return fmt.Errorf("failed to store schema version %d: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package dgraph_imei
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// ListenAndServe listens on the TCP address addr and serves the
+// XlsxService until ctx is done. See Serve.
+func (s *XlsxServer) ListenAndServe(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, lis)
+}
+
+// Serve serves the XlsxService, the gRPC health checking service and
+// reflection on lis until ctx is done. It then reports NOT_SERVING to
+// health checks, stops accepting requests and waits for the running ones
+// up to the shutdown timeout before it cancels them. Serve returns nil
+// after a shutdown.
+func (s *XlsxServer) Serve(ctx context.Context, lis net.Listener) error {
+	var opts []grpc.ServerOption
+	if s.tls != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tls)))
+	}
+	if s.maxStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(s.maxStreams))
+	}
+	if s.auth != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(s.unaryAuth),
+			grpc.ChainStreamInterceptor(s.streamAuth))
+	}
+	if s.requestTimeout > 0 {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(s.unaryTimeout),
+			grpc.ChainStreamInterceptor(s.streamTimeout))
+	}
+	gs := grpc.NewServer(opts...)
+	RegisterXlsxServiceServer(gs, s)
+	hs := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(gs, hs)
+	reflection.Register(gs)
+	hs.SetServingStatus(XlsxService_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		hs.Shutdown()
+		done := make(chan struct{})
+		go func() {
+			gs.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(s.shutdownTimeout):
+			gs.Stop()
+		}
+	}()
+
+	err := gs.Serve(lis)
+	if ctx.Err() != nil {
+		<-stopped
+		return nil
+	}
+	gs.Stop()
+	return err
+}
+
+// serviceMethod tells whether a method belongs to the XlsxService. Only
+// those are authenticated and limited by the request timeout: health checks
+// must work for probes without a token, and watching the health is a long
+// running stream.
+func serviceMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+XlsxService_ServiceDesc.ServiceName+"/")
+}
+
+func (s *XlsxServer) unaryTimeout(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !serviceMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+// streamTimeout runs the handler with a stream whose context expires after
+// the request timeout. A handler blocked on the stream does not see its
+// context, so the request is ended when the timeout expires. The handler
+// may then still be inside a SendMsg or RecvMsg call, which fails once the
+// request has ended; the stream refuses any later call, so the handler
+// returns without using the stream again.
+func (s *XlsxServer) streamTimeout(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !serviceMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, cancel := context.WithTimeout(ss.Context(), s.requestTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return status.Errorf(codes.DeadlineExceeded, "the request took longer than %s", s.requestTimeout)
+		}
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// contextStream replaces the context of a stream and refuses messages once
+// that context is done.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *contextStream) SendMsg(m any) error {
+	if err := s.ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *contextStream) RecvMsg(m any) error {
+	if err := s.ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return s.ServerStream.RecvMsg(m)
+}
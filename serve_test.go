@@ -0,0 +1,65 @@
+package dgraph_imei
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServe(t *testing.T) {
+	srv := newTestServer(t, ".", WithUploadDir(t.TempDir()), WithRequestTimeout(100*time.Millisecond))
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, lis) }()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	health := grpc_health_v1.NewHealthClient(conn)
+	resp, err := health.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: XlsxService_ServiceDesc.ServiceName})
+	if err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("health check = %v, %v, want SERVING", resp, err)
+	}
+
+	// An upload that never sends its metadata blocks the handler until the
+	// request times out.
+	stream, err := NewXlsxServiceClient(conn).UploadXlsx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.CloseAndRecv(); err == nil {
+		t.Error("an empty upload succeeded")
+	}
+	stream, err = NewXlsxServiceClient(conn).UploadXlsx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(new(UploadXlsxResponse)); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("stalled upload error = %v, want DeadlineExceeded", err)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve returned %v after the shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the shutdown")
+	}
+}
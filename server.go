@@ -0,0 +1,393 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	chunkSize              = 64 * 1024 // 64 KiB
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// uploadIDPattern matches the IDs of uploaded files: a random hex name with
+// the extension of the original file.
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}\.[0-9a-z]+$`)
+
+// defaultExtensions are the file extensions served unless the server is
+// configured otherwise: those of the supported formats.
+var defaultExtensions = []string{".xlsx", ".xlsm", ".csv", ".txt", ".tsv", ".tab", ".jsonl", ".ndjson", ".parquet"}
+
+// XlsxServer implements XlsxService for the files under a root directory.
+// File paths are relative to the root. Absolute paths, paths leaving the
+// root with .. or through a symlink, and files with an extension that is
+// not allowed are refused.
+type XlsxServer struct {
+	UnimplementedXlsxServiceServer
+	// root is absolute and free of symlinks.
+	root string
+	// uploadDir is where uploaded files are stored. Uploads are refused if
+	// it is empty.
+	uploadDir string
+	// maxUploadSize limits the size of uploaded files, if positive.
+	maxUploadSize int64
+	extensions    map[string]bool
+
+	maxStreams      uint32
+	requestTimeout  time.Duration
+	shutdownTimeout time.Duration
+	tlsFiles        *TLSFiles
+	tls             *tls.Config
+	auth            Authenticator
+	policy          AccessPolicy
+
+	// mapping and msisdn configure how ParseCalls parses the files.
+	mapping *MappingProfile
+	msisdn  MSISDNNormalizer
+}
+
+// ServerOption configures an XlsxServer created by NewXlsxServer.
+type ServerOption func(*XlsxServer)
+
+// WithUploadDir enables uploads and stores the uploaded files in dir. The
+// uploads are served by their upload ID, not as files under the root.
+func WithUploadDir(dir string) ServerOption {
+	return func(s *XlsxServer) {
+		s.uploadDir = dir
+	}
+}
+
+// WithMaxUploadSize refuses uploads larger than n bytes. Uploads are not
+// limited if n is not positive.
+func WithMaxUploadSize(n int64) ServerOption {
+	return func(s *XlsxServer) {
+		s.maxUploadSize = n
+	}
+}
+
+// WithExtensions sets the file extensions the server serves, like ".csv".
+// The extensions of all supported formats are allowed otherwise.
+func WithExtensions(extensions ...string) ServerOption {
+	return func(s *XlsxServer) {
+		s.extensions = make(map[string]bool, len(extensions))
+		for _, ext := range extensions {
+			s.extensions[strings.ToLower(ext)] = true
+		}
+	}
+}
+
+// WithMaxConcurrentStreams limits the number of concurrent requests on one
+// client connection. The limit of the gRPC transport applies if n is 0.
+func WithMaxConcurrentStreams(n uint32) ServerOption {
+	return func(s *XlsxServer) {
+		s.maxStreams = n
+	}
+}
+
+// WithRequestTimeout cancels XlsxService requests running longer than d.
+// Requests are not limited if d is not positive.
+func WithRequestTimeout(d time.Duration) ServerOption {
+	return func(s *XlsxServer) {
+		s.requestTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets how long Serve waits for running requests when it
+// shuts down before it cancels them. Non-positive values fall back to the
+// default.
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(s *XlsxServer) {
+		if d > 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// WithTLS serves over TLS with the certificate of files. If files has a
+// CA, clients must present a certificate signed by it (mutual TLS).
+func WithTLS(files TLSFiles) ServerOption {
+	return func(s *XlsxServer) {
+		s.tlsFiles = &files
+	}
+}
+
+// WithAuth requires XlsxService requests to carry a bearer token accepted
+// by auth. If policy is not nil, the identities may only read the files of
+// the directories it allows them. Health checks are not authenticated.
+func WithAuth(auth Authenticator, policy AccessPolicy) ServerOption {
+	return func(s *XlsxServer) {
+		s.auth, s.policy = auth, policy
+	}
+}
+
+// WithServerMappingProfile sets how ParseCalls finds sheets and columns in
+// the files. DefaultMappingProfile is used otherwise. NewXlsxServer
+// fails if the profile is nil or invalid.
+func WithServerMappingProfile(p *MappingProfile) ServerOption {
+	return func(s *XlsxServer) {
+		s.mapping = p
+	}
+}
+
+// WithServerMSISDNNormalizer sets the numbering plan ParseCalls uses to
+// convert MSDIN cells to E.164. DefaultMSISDNNormalizer is used otherwise.
+func WithServerMSISDNNormalizer(n MSISDNNormalizer) ServerOption {
+	return func(s *XlsxServer) {
+		s.msisdn = n
+	}
+}
+
+// NewXlsxServer returns a server for the files under root, which must be an
+// existing directory.
+func NewXlsxServer(root string, opts ...ServerOption) (*XlsxServer, error) {
+	root, err := filepath.Abs(root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid root directory: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("root %s is not a directory", root)
+	}
+
+	s := &XlsxServer{
+		root:            root,
+		shutdownTimeout: defaultShutdownTimeout,
+		mapping:         DefaultMappingProfile(),
+		msisdn:          DefaultMSISDNNormalizer,
+	}
+	WithExtensions(defaultExtensions...)(s)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := newRowParser(s.mapping, s.msisdn); err != nil {
+		return nil, fmt.Errorf("invalid mapping profile: %w", err)
+	}
+	if s.tlsFiles != nil {
+		if s.tls, err = s.tlsFiles.serverConfig(); err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// open opens an upload of the identity of the request by its ID or a file
+// under the root that the identity may read. The returned errors carry
+// gRPC status codes and do not reveal paths on the server.
+func (s *XlsxServer) open(ctx context.Context, filePath string) (*os.File, error) {
+	if !s.extensions[strings.ToLower(filepath.Ext(filePath))] {
+		return nil, status.Errorf(codes.InvalidArgument, "files like %q are not served", filePath)
+	}
+	var name string
+	inRoot := false
+	switch {
+	case s.uploadDir != "" && uploadIDPattern.MatchString(filePath):
+		name = filepath.Join(s.uploadDir, uploadOwnerDir(identityFrom(ctx)), filePath)
+	case filepath.IsAbs(filePath) || strings.HasPrefix(filePath, "/"):
+		return nil, status.Errorf(codes.InvalidArgument, "%q is not a path relative to the root", filePath)
+	case !filepath.IsLocal(filePath):
+		return nil, status.Errorf(codes.InvalidArgument, "%q leaves the root", filePath)
+	default:
+		resolved, err := filepath.EvalSymlinks(filepath.Join(s.root, filePath))
+		if err != nil {
+			return nil, fileError(filePath, err)
+		}
+		rel, err := filepath.Rel(s.root, resolved)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, status.Errorf(codes.PermissionDenied, "%q links outside of the root", filePath)
+		}
+		if err := s.authorize(ctx, filePath, rel); err != nil {
+			return nil, err
+		}
+		name, inRoot = resolved, true
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fileError(filePath, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fileError(filePath, err)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, status.Errorf(codes.InvalidArgument, "%q is not a regular file", filePath)
+	}
+	// A directory on the path may have been swapped for a symlink between
+	// EvalSymlinks and Open. The path must still be free of symlinks and
+	// name the file that was opened.
+	if inRoot && !samePath(name, info) {
+		file.Close()
+		return nil, status.Errorf(codes.PermissionDenied, "%q changed while it was opened", filePath)
+	}
+	return file, nil
+}
+
+// samePath tells whether name, a path without symlinks, still resolves to
+// itself and to the file described by info.
+func samePath(name string, info os.FileInfo) bool {
+	resolved, err := filepath.EvalSymlinks(name)
+	if err != nil || resolved != name {
+		return false
+	}
+	current, err := os.Stat(name)
+	return err == nil && os.SameFile(info, current)
+}
+
+// fileError converts an error opening the file to a gRPC status.
+func fileError(filePath string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return status.Errorf(codes.NotFound, "%q not found", filePath)
+	case errors.Is(err, fs.ErrPermission):
+		return status.Errorf(codes.PermissionDenied, "%q cannot be read", filePath)
+	default:
+		return status.Errorf(codes.Internal, "%q cannot be read", filePath)
+	}
+}
+
+// GetXlsxData streams the file from the requested offset, with the offset
+// and total size on every chunk. The last message carries the SHA-256 of
+// the whole file, so the bytes before the offset are hashed too.
+func (s *XlsxServer) GetXlsxData(req *GetXlsxRequest, stream XlsxService_GetXlsxDataServer) error {
+	file, err := s.open(stream.Context(), req.GetFilePath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size, offset := info.Size(), req.GetOffset()
+	if offset < 0 || offset > size {
+		return status.Errorf(codes.OutOfRange, "offset %d is outside of the file of %d bytes", offset, size)
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, file, offset); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		h.Write(buffer[:n])
+		if err := stream.Send(&XlsxDataChunk{Chunk: buffer[:n], Offset: offset, TotalSize: size}); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+
+	return stream.Send(&XlsxDataChunk{Offset: offset, TotalSize: size, Sha256: hex.EncodeToString(h.Sum(nil))})
+}
+
+// UploadXlsx stores an uploaded file under a new upload ID. The file is
+// written to a temporary name and only renamed once it is complete.
+func (s *XlsxServer) UploadXlsx(stream XlsxService_UploadXlsxServer) error {
+	if s.uploadDir == "" {
+		return status.Error(codes.FailedPrecondition, "uploads are disabled")
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first upload message must carry the file metadata")
+	}
+	if _, err := formatFromName(meta.GetFileName()); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := newUploadID(meta.GetFileName())
+	if err != nil {
+		return err
+	}
+	if !s.extensions[filepath.Ext(id)] {
+		return status.Errorf(codes.InvalidArgument, "files like %q are not served", meta.GetFileName())
+	}
+	if s.maxUploadSize > 0 && meta.GetSize() > s.maxUploadSize {
+		return status.Errorf(codes.ResourceExhausted, "the file of %d bytes exceeds the upload limit of %d bytes", meta.GetSize(), s.maxUploadSize)
+	}
+
+	dir := filepath.Join(s.uploadDir, uploadOwnerDir(identityFrom(stream.Context())))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var size int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetMetadata() != nil {
+			return status.Error(codes.InvalidArgument, "the file metadata must only be sent in the first upload message")
+		}
+		// The size is checked before writing, so a client cannot fill the
+		// disk by sending more than it announced or than the limit.
+		size += int64(len(req.GetChunk()))
+		if s.maxUploadSize > 0 && size > s.maxUploadSize {
+			return status.Errorf(codes.ResourceExhausted, "the upload exceeds the limit of %d bytes", s.maxUploadSize)
+		}
+		if meta.GetSize() != 0 && size > meta.GetSize() {
+			return status.Errorf(codes.ResourceExhausted, "the upload exceeds the announced size of %d bytes", meta.GetSize())
+		}
+		if _, err := file.Write(req.GetChunk()); err != nil {
+			return err
+		}
+	}
+	if meta.GetSize() != 0 && size != meta.GetSize() {
+		return status.Errorf(codes.InvalidArgument, "received %d bytes, the metadata announced %d", size, meta.GetSize())
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(dir, id)); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&UploadXlsxResponse{UploadId: id, Size: size})
+}
+
+// newUploadID returns a random upload ID with the extension of fileName.
+func newUploadID(fileName string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + strings.ToLower(filepath.Ext(fileName)), nil
+}
@@ -0,0 +1,156 @@
+package dgraph_imei
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestServer(t *testing.T, root string, opts ...ServerOption) *XlsxServer {
+	t.Helper()
+	srv, err := NewXlsxServer(root, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestXlsxServerSandbox(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	for _, d := range []string{filepath.Join(root, "calls"), filepath.Join(dir, "secret")} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "calls", "march.csv"): "march",
+		filepath.Join(root, "notes.md"):           "notes",
+		filepath.Join(dir, "secret", "calls.csv"): "secret",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "calls", "april.csv"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		filepath.Join(root, "escape.csv"): filepath.Join(dir, "secret", "calls.csv"),
+		filepath.Join(root, "secret"):     filepath.Join(dir, "secret"),
+		filepath.Join(root, "march.csv"):  filepath.Join("calls", "march.csv"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("cannot create symlinks: %v", err)
+		}
+	}
+	client := startTestServer(t, newTestServer(t, root))
+
+	tests := []struct {
+		path string
+		code codes.Code
+	}{
+		{"calls/march.csv", codes.OK},
+		{"./calls/../calls/march.csv", codes.OK},
+		{"march.csv", codes.OK},
+		{filepath.Join(root, "calls", "march.csv"), codes.InvalidArgument},
+		{"/etc/passwd.csv", codes.InvalidArgument},
+		{"../secret/calls.csv", codes.InvalidArgument},
+		{"calls/../../secret/calls.csv", codes.InvalidArgument},
+		{"escape.csv", codes.PermissionDenied},
+		{"secret/calls.csv", codes.PermissionDenied},
+		{"notes.md", codes.InvalidArgument},
+		{"calls/april.csv", codes.InvalidArgument},
+		{"calls/may.csv", codes.NotFound},
+	}
+	for _, tt := range tests {
+		_, _, err := fetchFile(context.Background(), client, tt.path)
+		if code := status.Code(unwrapAll(err)); code != tt.code {
+			t.Errorf("%s: error = %v, want code %s", tt.path, err, tt.code)
+		}
+	}
+}
+
+func TestXlsxServerUploadsDisabled(t *testing.T) {
+	client := startTestServer(t, newTestServer(t, "."))
+	cli := &FileClient{xlsxClient: client}
+	_, err := cli.UploadFile(context.Background(), "test_file.xlsx")
+	if status.Code(unwrapAll(err)) != codes.FailedPrecondition {
+		t.Errorf("error = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestXlsxServerMappingProfile(t *testing.T) {
+	for _, p := range []*MappingProfile{nil, {SheetIndex: -1}} {
+		if _, err := NewXlsxServer(".", WithServerMappingProfile(p)); err == nil {
+			t.Errorf("NewXlsxServer accepted mapping profile %+v", p)
+		}
+	}
+}
+
+// unwrapAll returns the innermost error wrapped by err.
+func unwrapAll(err error) error {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err
+		}
+		err = inner
+	}
+}
+
+// TestSamePath covers the check open makes after opening a file, since the
+// swaps it detects cannot be timed reliably in a test.
+func TestSamePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "data", "calls.csv")
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("calls"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !samePath(name, info) {
+		t.Error("unchanged file reported as changed")
+	}
+
+	// The file is replaced by another one.
+	if err := os.Rename(name, name+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if samePath(name, info) {
+		t.Error("replaced file reported as unchanged")
+	}
+
+	// The directory is swapped for a symlink to a copy elsewhere.
+	outside := filepath.Join(dir, "outside")
+	if err := os.Rename(filepath.Dir(name), outside); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Dir(name)); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Stat(name); err != nil {
+		t.Fatal(err)
+	}
+	if samePath(name, info) {
+		t.Error("path through a symlink reported as unchanged")
+	}
+}
@@ -0,0 +1,186 @@
+package dgraph_imei
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format identifies the file format of an ingested file.
+type Format string
+
+const (
+	FormatXLSX    Format = "xlsx"
+	FormatCSV     Format = "csv"
+	FormatTSV     Format = "tsv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// formatFromName picks the format from the file extension.
+func formatFromName(name string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".xlsx", ".xlsm":
+		return FormatXLSX, nil
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".tsv", ".tab":
+		return FormatTSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".parquet":
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %q from its extension", name)
+	}
+}
+
+// record is a row of an ingested file with its position in the file.
+type record struct {
+	sheet string
+	row   int // 1-based
+	cells []string
+	// reject is set instead of cells when the row could not be decoded.
+	reject *Rejection
+}
+
+// recordSource yields the rows of a file one at a time. Every file format
+// implements it, so rows from all formats go through the same header
+// mapping, validation and Dgraph writer.
+type recordSource interface {
+	// next returns the next row, or io.EOF after the last one.
+	next() (record, error)
+	Close() error
+}
+
+// namedFieldSource is implemented by formats whose records name their
+// fields, like JSON Lines and Parquet. Such sources have no header row and
+// yield their cells in the order of columnNames.
+type namedFieldSource interface {
+	recordSource
+	namedFields()
+}
+
+// openSource opens the spooled file in the given format.
+func openSource(format Format, name string, file *os.File, parser *rowParser) (recordSource, error) {
+	switch format {
+	case FormatXLSX:
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return newXLSXSource(file, info.Size(), parser)
+	case FormatCSV, FormatTSV:
+		return newCSVSource(file, filepath.Base(name), format == FormatTSV)
+	case FormatJSONL:
+		return newJSONLSource(file, filepath.Base(name)), nil
+	case FormatParquet:
+		return newParquetSource(file, filepath.Base(name))
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// sheetLister is implemented by sources that read several sheets, so
+// sheets that yield no rows are reported too.
+type sheetLister interface {
+	sheetNames() []string
+}
+
+// readRecords maps the header row of every sheet of src, then validates the
+// following rows and hands the resulting calls to handle along with the
+// position of their record, counting every record read from src. The first
+// skip records were processed by an earlier run; only their header rows are
+// read again. Sheets without a recognisable call header are skipped; it is
+// an error if no sheet has one.
+func readRecords(ctx context.Context, src recordSource, parser *rowParser, report *Report, skip int, handle func(call *Call, pos int) error) error {
+	headerRow := parser.mapping.headerRow()
+	var fields *columnLayout
+	if _, ok := src.(namedFieldSource); ok {
+		headerRow, fields = 0, parser.mapping.fieldLayout()
+	}
+	if l, ok := src.(sheetLister); ok {
+		for _, name := range l.sheetNames() {
+			report.sheet(name)
+		}
+	}
+
+	var layout *columnLayout
+	sheet := -1
+	for pos := 1; ; pos++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rec, err := src.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if sheet < 0 || report.Sheets[sheet].Name != rec.sheet {
+			sheet, layout = report.sheet(rec.sheet), fields
+			report.Sheets[sheet].Header = layout != nil
+		}
+		stats := &report.Sheets[sheet]
+		switch {
+		case rec.row < headerRow:
+			continue
+		case rec.row == headerRow:
+			if layout, err = parser.mapping.layout(rec.cells); err != nil {
+				stats.Detail = err.Error()
+			}
+			stats.Header = layout != nil
+			continue
+		case layout == nil, pos <= skip:
+			continue
+		case rec.reject != nil:
+		case isBlankRow(rec.cells):
+			continue
+		}
+		var call *Call
+		rej := rec.reject
+		if rej == nil {
+			call, rej = parser.parseRow(layout, rec.cells)
+		}
+		if rej != nil {
+			rej.Sheet, rej.Row = rec.sheet, rec.row
+			report.Rejected = append(report.Rejected, *rej)
+			stats.Rejected++
+			continue
+		}
+		if err := handle(call, pos); err != nil {
+			return err
+		}
+		report.Accepted++
+		stats.Accepted++
+	}
+	return checkHeaders(report, headerRow, fields != nil)
+}
+
+// checkHeaders explains why sheets were skipped and fails if none had a
+// call header. Empty files of formats without a header row are fine.
+func checkHeaders(report *Report, headerRow int, headerless bool) error {
+	var skipped []string
+	for i := range report.Sheets {
+		stats := &report.Sheets[i]
+		if stats.Header {
+			continue
+		}
+		if stats.Detail == "" {
+			stats.Detail = fmt.Sprintf("no header row %d", headerRow)
+		}
+		skipped = append(skipped, fmt.Sprintf("sheet %s: %s", stats.Name, stats.Detail))
+	}
+	switch {
+	case headerless || len(skipped) < len(report.Sheets):
+		return nil
+	case len(skipped) == 0:
+		return fmt.Errorf("the file is empty")
+	default:
+		return fmt.Errorf("no sheet has a call header: %s", strings.Join(skipped, "; "))
+	}
+}
@@ -0,0 +1,240 @@
+package dgraph_imei
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLSFiles names the PEM files of a TLS configuration. The certificate, the
+// key and the CA certificates are read again when the files change, so
+// rotated certificates are used by new connections without a restart.
+type TLSFiles struct {
+	// CAFile holds the CA certificates that verify the peer. Clients use
+	// the system pool if it is empty. Servers require client certificates
+	// signed by these CAs if it is set (mutual TLS) and accept clients
+	// without certificates otherwise.
+	CAFile string
+	// CertFile and KeyFile hold the certificate of this side. Servers must
+	// have one; clients send theirs to servers requiring mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name clients expect in the server
+	// certificate, which is the host of the dialed address otherwise.
+	ServerName string
+}
+
+// clientConfig returns the TLS configuration of a client.
+func (f *TLSFiles) clientConfig() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: f.ServerName, MinVersion: tls.VersionTLS12}
+	if f.CertFile != "" || f.KeyFile != "" {
+		certs, err := newCertReloader(f.CertFile, f.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.get(), nil
+		}
+	}
+	if f.CAFile != "" {
+		cas, err := newCAReloader(f.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		// The roots are checked in VerifyConnection rather than by the
+		// standard verification so a rotated CA file is picked up.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyChain(cs, cas.get(), x509.ExtKeyUsageServerAuth, cfg.ServerName)
+		}
+	}
+	return cfg, nil
+}
+
+// serverConfig returns the TLS configuration of a server.
+func (f *TLSFiles) serverConfig() (*tls.Config, error) {
+	if f.CertFile == "" || f.KeyFile == "" {
+		return nil, errors.New("a TLS server needs a certificate and a key")
+	}
+	certs, err := newCertReloader(f.CertFile, f.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return certs.get(), nil
+		},
+	}
+	if f.CAFile != "" {
+		cas, err := newCAReloader(f.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyChain(cs, cas.get(), x509.ExtKeyUsageClientAuth, "")
+		}
+	}
+	return cfg, nil
+}
+
+// verifyChain verifies the peer certificates of a connection against roots.
+// serverName is checked unless it is empty.
+func verifyChain(cs tls.ConnectionState, roots *x509.CertPool, usage x509.ExtKeyUsage, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: the peer sent no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	if usage == x509.ExtKeyUsageServerAuth {
+		opts.DNSName = serverName
+		if opts.DNSName == "" {
+			opts.DNSName = cs.ServerName
+		}
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// transportCredentials returns the dial option for f, or insecure
+// credentials if f is nil.
+func transportCredentials(f *TLSFiles) (grpc.DialOption, error) {
+	if f == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	cfg, err := f.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(cfg)), nil
+}
+
+// fileReloader caches a value loaded from files and loads it again when
+// the modification time or the size of one of the files changes. A failed
+// reload keeps the previous value, since a rotation may be caught halfway
+// through writing the files.
+type fileReloader[T any] struct {
+	files []string
+	load  func() (T, error)
+
+	mu      sync.Mutex
+	value   T
+	stamps  []fileStamp
+	checked time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloadInterval limits how often the files are checked for changes.
+var reloadInterval = time.Second
+
+func newFileReloader[T any](load func() (T, error), files ...string) (*fileReloader[T], error) {
+	r := &fileReloader[T]{files: files, load: load}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if r.value, err = load(); err != nil {
+		return nil, err
+	}
+	r.stamps, r.checked = stamps, time.Now()
+	return r, nil
+}
+
+func (r *fileReloader[T]) stat() ([]fileStamp, error) {
+	stamps := make([]fileStamp, len(r.files))
+	for i, name := range r.files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps[i] = fileStamp{info.ModTime(), info.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *fileReloader[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) < reloadInterval {
+		return r.value
+	}
+	r.checked = time.Now()
+
+	stamps, err := r.stat()
+	if err != nil {
+		log.Printf("Failed to check %v for changes: %v", r.files, err)
+		return r.value
+	}
+	if stampsEqual(stamps, r.stamps) {
+		return r.value
+	}
+	value, err := r.load()
+	if err != nil {
+		log.Printf("Failed to reload %v, keeping the previous version: %v", r.files, err)
+		return r.value
+	}
+	r.value, r.stamps = value, stamps
+	log.Printf("Reloaded %v", r.files)
+	return r.value
+}
+
+func stampsEqual(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+func newCertReloader(certFile, keyFile string) (*fileReloader[*tls.Certificate], error) {
+	r, err := newFileReloader(func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		return &cert, err
+	}, certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the TLS certificate: %w", err)
+	}
+	return r, nil
+}
+
+func newCAReloader(caFile string) (*fileReloader[*x509.CertPool], error) {
+	r, err := newFileReloader(func() (*x509.CertPool, error) {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s holds no PEM certificates", caFile)
+		}
+		return pool, nil
+	}, caFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the CA certificates: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,210 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCert is a certificate written to PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+var testSerial int64
+
+// newTestCert issues a certificate signed by parent, or a self-signed CA if
+// parent is nil, and writes it to dir.
+func newTestCert(t *testing.T, dir, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSerial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(testSerial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		tmpl.ExtKeyUsage, tmpl.DNSNames = nil, nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testCert{cert: cert, key: key, certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+"-key.pem")}
+	writePEM(t, c.certFile, "CERTIFICATE", der)
+	writePEM(t, c.keyFile, "EC PRIVATE KEY", keyDER)
+	return c
+}
+
+func writePEM(t *testing.T, name, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copyTo overwrites the files of c with those of other. The files are
+// dated ahead so the change is seen on file systems with coarse timestamps.
+func (c *testCert) copyTo(t *testing.T, other *testCert) {
+	t.Helper()
+	for src, dst := range map[string]string{other.certFile: c.certFile, other.keyFile: c.keyFile} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(dst, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// startTLSServer serves srv and returns a function dialing it with the
+// given client files, or without TLS if they are nil.
+func startTLSServer(t *testing.T, srv *XlsxServer) func(*TLSFiles, ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go srv.Serve(ctx, lis)
+
+	return func(files *TLSFiles, opts ...grpc.DialOption) *grpc.ClientConn {
+		t.Helper()
+		creds, err := transportCredentials(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, creds, grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }))
+		conn, err := grpc.Dial("bufnet", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+}
+
+// healthCheck tells whether a new connection with the given client files
+// passes a health check.
+func healthCheck(dial func(*TLSFiles, ...grpc.DialOption) *grpc.ClientConn) func(*TLSFiles) error {
+	return func(files *TLSFiles) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := grpc_health_v1.NewHealthClient(dial(files)).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		return err
+	}
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, 0)
+	serverCert := newTestCert(t, dir, "server", ca, x509.ExtKeyUsageServerAuth)
+	otherCA := newTestCert(t, dir, "other-ca", nil, 0)
+
+	check := healthCheck(startTLSServer(t, newTestServer(t, ".", WithTLS(TLSFiles{CertFile: serverCert.certFile, KeyFile: serverCert.keyFile}))))
+	if err := check(&TLSFiles{CAFile: ca.certFile, ServerName: "localhost"}); err != nil {
+		t.Errorf("client trusting the CA: %v", err)
+	}
+	if err := check(&TLSFiles{CAFile: otherCA.certFile, ServerName: "localhost"}); err == nil {
+		t.Error("client trusting another CA connected")
+	}
+	if err := check(&TLSFiles{CAFile: ca.certFile, ServerName: "example.com"}); err == nil {
+		t.Error("client expecting another server name connected")
+	}
+	if err := check(nil); err == nil {
+		t.Error("client without TLS connected")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, 0)
+	serverCert := newTestCert(t, dir, "server", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCert(t, dir, "client", ca, x509.ExtKeyUsageClientAuth)
+	otherCA := newTestCert(t, dir, "other-ca", nil, 0)
+	strangerCert := newTestCert(t, dir, "stranger", otherCA, x509.ExtKeyUsageClientAuth)
+
+	check := healthCheck(startTLSServer(t, newTestServer(t, ".", WithTLS(TLSFiles{CAFile: ca.certFile, CertFile: serverCert.certFile, KeyFile: serverCert.keyFile}))))
+	if err := check(&TLSFiles{CAFile: ca.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile, ServerName: "localhost"}); err != nil {
+		t.Errorf("client with a certificate: %v", err)
+	}
+	if err := check(&TLSFiles{CAFile: ca.certFile, ServerName: "localhost"}); err == nil {
+		t.Error("client without a certificate connected")
+	}
+	if err := check(&TLSFiles{CAFile: ca.certFile, CertFile: strangerCert.certFile, KeyFile: strangerCert.keyFile, ServerName: "localhost"}); err == nil {
+		t.Error("client with a certificate of another CA connected")
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	interval := reloadInterval
+	reloadInterval = 0
+	t.Cleanup(func() { reloadInterval = interval })
+
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, 0)
+	serverCert := newTestCert(t, dir, "server", ca, x509.ExtKeyUsageServerAuth)
+	newCA := newTestCert(t, dir, "new-ca", nil, 0)
+	newServerCert := newTestCert(t, dir, "new-server", newCA, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCert(t, dir, "client", ca, x509.ExtKeyUsageClientAuth)
+	newClientCert := newTestCert(t, dir, "new-client", newCA, x509.ExtKeyUsageClientAuth)
+
+	// The server trusts the CA file of the clients, which is rotated too.
+	clientCA := newTestCert(t, dir, "client-ca", nil, 0)
+	clientCA.copyTo(t, ca)
+	check := healthCheck(startTLSServer(t, newTestServer(t, ".", WithTLS(TLSFiles{CAFile: clientCA.certFile, CertFile: serverCert.certFile, KeyFile: serverCert.keyFile}))))
+	newClient := &TLSFiles{CAFile: newCA.certFile, CertFile: newClientCert.certFile, KeyFile: newClientCert.keyFile, ServerName: "localhost"}
+	if err := check(newClient); err == nil {
+		t.Fatal("client of the new CA connected before the rotation")
+	}
+
+	serverCert.copyTo(t, newServerCert)
+	clientCA.copyTo(t, newCA)
+	if err := check(newClient); err != nil {
+		t.Errorf("client of the new CA after the rotation: %v", err)
+	}
+	if err := check(&TLSFiles{CAFile: ca.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile, ServerName: "localhost"}); err == nil {
+		t.Error("client of the old CA connected after the rotation")
+	}
+}
@@ -0,0 +1,142 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxTransferResumes is the number of times in a row an interrupted transfer
+// is resumed from the last received offset without receiving any data
+// before fetchFile gives up.
+const maxTransferResumes = 3
+
+// errCorruptTransfer is returned when the received file does not match the
+// size or the SHA-256 digest announced by the server.
+var errCorruptTransfer = errors.New("corrupt file transfer")
+
+// fetchFile streams the file from the gRPC server into a temporary file and
+// returns it with the hex SHA-256 hash of its content. Interrupted streams
+// are resumed from the last received offset, and the file is checked
+// against the digest sent by the server before it is returned. The caller
+// must close and remove the file.
+func fetchFile(ctx context.Context, client XlsxServiceClient, filePath string) (*os.File, string, error) {
+	spool, err := os.CreateTemp("", "dgraph_imei-*")
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := transferFile(ctx, client, filePath, spool)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, "", err
+	}
+	return spool, hash, nil
+}
+
+// transferFile writes the file to w, resuming the transfer after transient
+// errors, and returns its verified hex SHA-256 hash.
+func transferFile(ctx context.Context, client XlsxServiceClient, filePath string, w io.Writer) (string, error) {
+	t := &transfer{w: w, h: sha256.New(), total: -1}
+	for resumes := 0; ; resumes++ {
+		offset := t.offset
+		err := t.receive(ctx, client, filePath)
+		if err == nil {
+			break
+		}
+		// Streams cut by the request timeout of the server make progress,
+		// so large files are fetched over as many streams as they need.
+		if t.offset > offset {
+			resumes = 0
+		}
+		if resumes >= maxTransferResumes || !transient(err) || ctx.Err() != nil {
+			return "", fmt.Errorf("could not fetch file data: %w", err)
+		}
+		log.Printf("Resuming the transfer of %s at byte %d: %v", filePath, t.offset, err)
+	}
+	return t.verify()
+}
+
+// transfer is the state of a file transfer kept across resumed streams.
+type transfer struct {
+	w      io.Writer
+	h      hash.Hash
+	offset int64
+	total  int64
+	digest string
+}
+
+// receive requests the file from the current offset and writes the
+// received chunks until the server sends the digest.
+func (t *transfer) receive(ctx context.Context, client XlsxServiceClient, filePath string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.GetXlsxData(ctx, &GetXlsxRequest{FilePath: filePath, Offset: t.offset})
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			if t.digest == "" {
+				return fmt.Errorf("%w: the stream ended without a digest", errCorruptTransfer)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if chunk.Offset != t.offset {
+			return fmt.Errorf("%w: received a chunk at byte %d, expected byte %d", errCorruptTransfer, chunk.Offset, t.offset)
+		}
+		if t.total >= 0 && chunk.TotalSize != t.total {
+			return fmt.Errorf("%w: the file size changed from %d to %d bytes", errCorruptTransfer, t.total, chunk.TotalSize)
+		}
+		t.total = chunk.TotalSize
+		if _, err := t.w.Write(chunk.Chunk); err != nil {
+			return err
+		}
+		t.h.Write(chunk.Chunk)
+		t.offset += int64(len(chunk.Chunk))
+		if chunk.Sha256 != "" {
+			t.digest = chunk.Sha256
+		}
+	}
+}
+
+// verify checks the received bytes against the size and digest announced
+// by the server and returns the hex SHA-256 hash of the file.
+func (t *transfer) verify() (string, error) {
+	if t.offset != t.total {
+		return "", fmt.Errorf("%w: received %d bytes, the server announced %d", errCorruptTransfer, t.offset, t.total)
+	}
+	hash := hex.EncodeToString(t.h.Sum(nil))
+	if hash != t.digest {
+		return "", fmt.Errorf("%w: the file has SHA-256 %s, the server announced %s", errCorruptTransfer, hash, t.digest)
+	}
+	return hash, nil
+}
+
+// transient tells whether a transfer that failed with err may succeed when
+// it is resumed. DeadlineExceeded is included for the request timeout of the
+// server; the deadline of the caller is checked separately.
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.Internal, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package dgraph_imei
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyServer fails every stream after sending the given number of chunks,
+// a given number of times, with err or, if it is nil, Unavailable.
+type flakyServer struct {
+	*XlsxServer
+	chunks   int
+	failures int
+	err      error
+	offsets  []int64
+}
+
+func (s *flakyServer) GetXlsxData(req *GetXlsxRequest, stream XlsxService_GetXlsxDataServer) error {
+	s.offsets = append(s.offsets, req.GetOffset())
+	if s.failures == 0 {
+		return s.XlsxServer.GetXlsxData(req, stream)
+	}
+	s.failures--
+	err := s.err
+	if err == nil {
+		err = status.Error(codes.Unavailable, "connection reset")
+	}
+	return s.XlsxServer.GetXlsxData(req, &failingStream{XlsxService_GetXlsxDataServer: stream, left: s.chunks, err: err})
+}
+
+type failingStream struct {
+	XlsxService_GetXlsxDataServer
+	left int
+	err  error
+}
+
+func (s *failingStream) Send(chunk *XlsxDataChunk) error {
+	if s.left == 0 {
+		return s.err
+	}
+	s.left--
+	return s.XlsxService_GetXlsxDataServer.Send(chunk)
+}
+
+// corruptServer announces the digest of other content.
+type corruptServer struct {
+	*XlsxServer
+}
+
+func (s *corruptServer) GetXlsxData(req *GetXlsxRequest, stream XlsxService_GetXlsxDataServer) error {
+	return s.XlsxServer.GetXlsxData(req, &corruptStream{stream})
+}
+
+type corruptStream struct {
+	XlsxService_GetXlsxDataServer
+}
+
+func (s *corruptStream) Send(chunk *XlsxDataChunk) error {
+	if chunk.Sha256 != "" {
+		sum := sha256.Sum256([]byte("other"))
+		chunk.Sha256 = hex.EncodeToString(sum[:])
+	}
+	return s.XlsxService_GetXlsxDataServer.Send(chunk)
+}
+
+func TestFetchFileResumes(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 3*chunkSize/10)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "calls.csv"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := &flakyServer{XlsxServer: newTestServer(t, root), chunks: 1, failures: 2}
+	client := startTestServer(t, srv)
+
+	spool, hash, err := fetchFile(context.Background(), client, "calls.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	got, err := io.ReadAll(spool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("fetched %d bytes that differ from the %d bytes of the file", len(got), len(data))
+	}
+	if sum := sha256.Sum256(data); hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("fetched file has hash %s, want %x", hash, sum)
+	}
+	want := []int64{0, chunkSize, 2 * chunkSize}
+	if len(srv.offsets) != len(want) {
+		t.Fatalf("requested offsets %v, want %v", srv.offsets, want)
+	}
+	for i := range want {
+		if srv.offsets[i] != want[i] {
+			t.Fatalf("requested offsets %v, want %v", srv.offsets, want)
+		}
+	}
+}
+
+func TestFetchFileResumesTimedOutStreams(t *testing.T) {
+	// Streams cut by the request timeout are resumed as long as they make
+	// progress, more often than maxTransferResumes.
+	failures := maxTransferResumes + 2
+	data := bytes.Repeat([]byte("0123456789"), (failures+1)*chunkSize/10)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "calls.csv"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	timeout := status.Error(codes.DeadlineExceeded, "the request took longer than 10m0s")
+	srv := &flakyServer{XlsxServer: newTestServer(t, root), chunks: 1, failures: failures, err: timeout}
+	client := startTestServer(t, srv)
+
+	spool, _, err := fetchFile(context.Background(), client, "calls.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	if len(srv.offsets) != failures+1 {
+		t.Errorf("requested offsets %v, want %d streams", srv.offsets, failures+1)
+	}
+}
+
+func TestFetchFileGivesUp(t *testing.T) {
+	client := startTestServer(t, &flakyServer{XlsxServer: newTestServer(t, "."), failures: maxTransferResumes + 1})
+	_, _, err := fetchFile(context.Background(), client, "test_file.xlsx")
+	if status.Code(errors.Unwrap(err)) != codes.Unavailable {
+		t.Errorf("error = %v, want the stream error", err)
+	}
+}
+
+func TestFetchFileRejectsCorruptTransfer(t *testing.T) {
+	client := startTestServer(t, &corruptServer{newTestServer(t, ".")})
+	_, _, err := fetchFile(context.Background(), client, "test_file.xlsx")
+	if !errors.Is(err, errCorruptTransfer) {
+		t.Errorf("error = %v, want %v", err, errCorruptTransfer)
+	}
+}
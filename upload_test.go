@@ -0,0 +1,142 @@
+package dgraph_imei
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startTestServer serves the XlsxService over an in-memory connection and
+// returns a client connected to it.
+func startTestServer(t *testing.T, srv XlsxServiceServer) XlsxServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterXlsxServiceServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewXlsxServiceClient(conn)
+}
+
+func TestUploadFile(t *testing.T) {
+	client := startTestServer(t, newTestServer(t, ".", WithUploadDir(t.TempDir())))
+	cli := &FileClient{xlsxClient: client}
+	ctx := context.Background()
+
+	id, err := cli.UploadFile(ctx, "test_file.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uploadIDPattern.MatchString(id) || !strings.HasSuffix(id, ".xlsx") {
+		t.Fatalf("upload ID %q", id)
+	}
+
+	spool, hash, err := fetchFile(ctx, client, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	data, err := os.ReadFile("test_file.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(data); hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("downloaded upload has hash %s, want %x", hash, sum)
+	}
+}
+
+func TestUploadRejectsInvalidStreams(t *testing.T) {
+	dir := t.TempDir()
+	client := startTestServer(t, newTestServer(t, ".", WithUploadDir(dir), WithMaxUploadSize(8)))
+	tests := []struct {
+		name     string
+		messages []*UploadXlsxRequest
+		code     codes.Code
+		err      string
+	}{
+		{
+			name:     "no metadata",
+			messages: []*UploadXlsxRequest{{Data: &UploadXlsxRequest_Chunk{Chunk: []byte("data")}}},
+			err:      "must carry the file metadata",
+		},
+		{
+			name:     "unknown format",
+			messages: []*UploadXlsxRequest{{Data: &UploadXlsxRequest_Metadata{Metadata: &UploadMetadata{FileName: "calls.exe"}}}},
+			err:      "cannot tell the format",
+		},
+		{
+			name: "short",
+			messages: []*UploadXlsxRequest{
+				{Data: &UploadXlsxRequest_Metadata{Metadata: &UploadMetadata{FileName: "calls.csv", Size: 6}}},
+				{Data: &UploadXlsxRequest_Chunk{Chunk: []byte("data")}},
+			},
+			err: "received 4 bytes, the metadata announced 6",
+		},
+		{
+			name:     "announced too large",
+			messages: []*UploadXlsxRequest{{Data: &UploadXlsxRequest_Metadata{Metadata: &UploadMetadata{FileName: "calls.csv", Size: 9}}}},
+			code:     codes.ResourceExhausted,
+			err:      "exceeds the upload limit of 8 bytes",
+		},
+		{
+			name: "too large",
+			messages: []*UploadXlsxRequest{
+				{Data: &UploadXlsxRequest_Metadata{Metadata: &UploadMetadata{FileName: "calls.csv"}}},
+				{Data: &UploadXlsxRequest_Chunk{Chunk: []byte("data")}},
+				{Data: &UploadXlsxRequest_Chunk{Chunk: []byte("data!")}},
+			},
+			code: codes.ResourceExhausted,
+			err:  "exceeds the limit of 8 bytes",
+		},
+		{
+			name: "longer than announced",
+			messages: []*UploadXlsxRequest{
+				{Data: &UploadXlsxRequest_Metadata{Metadata: &UploadMetadata{FileName: "calls.csv", Size: 4}}},
+				{Data: &UploadXlsxRequest_Chunk{Chunk: []byte("data!")}},
+			},
+			code: codes.ResourceExhausted,
+			err:  "exceeds the announced size of 4 bytes",
+		},
+	}
+	for _, tt := range tests {
+		stream, err := client.UploadXlsx(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range tt.messages {
+			if err := stream.Send(m); err != nil {
+				break
+			}
+		}
+		_, err = stream.CloseAndRecv()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+		if tt.code != codes.OK && status.Code(err) != tt.code {
+			t.Errorf("%s: code = %v, want %v", tt.name, status.Code(err), tt.code)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Errorf("failed uploads left files %v", files)
+	}
+}
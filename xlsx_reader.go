@@ -0,0 +1,505 @@
+package dgraph_imei
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The parts of an XLSX package are read here with archive/zip rather than
+// with excelize: excelize.OpenReader reads the whole archive into memory
+// before its Rows iterator can be used, while a zip.Reader over the spooled
+// file inflates one part at a time.
+
+// xlsxWorkbook is the part of an XLSX package needed to read its cells:
+// the sheets in workbook order and the shared strings table.
+type xlsxWorkbook struct {
+	files    map[string]*zip.File
+	sheets   []xlsxSheet
+	date1904 bool
+	strings  *sharedStrings
+}
+
+type xlsxSheet struct {
+	name string
+	path string
+}
+
+const (
+	relTypeOfficeDocument = "/officeDocument"
+	relTypeSharedStrings  = "/sharedStrings"
+)
+
+// openXLSXWorkbook reads the workbook part and the shared strings of a
+// package. The worksheets are left in the archive to be streamed. The
+// workbook must be closed to remove the spooled shared strings.
+func openXLSXWorkbook(r io.ReaderAt, size int64) (*xlsxWorkbook, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	wb := &xlsxWorkbook{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		wb.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	workbookPath := "xl/workbook.xml"
+	rels, err := wb.relationships("_rels/.rels", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.typ, relTypeOfficeDocument) {
+			workbookPath = rel.target
+		}
+	}
+	if rels, err = wb.relationships(path.Join(path.Dir(workbookPath), "_rels", path.Base(workbookPath)+".rels"), path.Dir(workbookPath)); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels))
+	var sharedStrings string
+	for _, rel := range rels {
+		targets[rel.id] = rel.target
+		if strings.HasSuffix(rel.typ, relTypeSharedStrings) {
+			sharedStrings = rel.target
+		}
+	}
+	if err := wb.readWorkbook(workbookPath, targets); err != nil {
+		return nil, err
+	}
+	if sharedStrings != "" {
+		if wb.strings, err = wb.readSharedStrings(sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+	return wb, nil
+}
+
+func (wb *xlsxWorkbook) Close() error {
+	if wb.strings == nil {
+		return nil
+	}
+	return wb.strings.Close()
+}
+
+func (wb *xlsxWorkbook) open(name string) (io.ReadCloser, error) {
+	f, ok := wb.files[name]
+	if !ok {
+		return nil, fmt.Errorf("the package has no part %s", name)
+	}
+	return f.Open()
+}
+
+type xlsxRelationship struct {
+	id, typ, target string
+}
+
+// relationships reads a relationships part, resolving the targets against
+// dir. A missing part has no relationships.
+func (wb *xlsxWorkbook) relationships(name, dir string) ([]xlsxRelationship, error) {
+	if _, ok := wb.files[name]; !ok {
+		return nil, nil
+	}
+	rc, err := wb.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var doc struct {
+		Relationships []struct {
+			ID         string `xml:"Id,attr"`
+			Type       string `xml:"Type,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	rels := make([]xlsxRelationship, 0, len(doc.Relationships))
+	for _, r := range doc.Relationships {
+		if r.TargetMode == "External" {
+			continue
+		}
+		target := r.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		rels = append(rels, xlsxRelationship{id: r.ID, typ: r.Type, target: target})
+	}
+	return rels, nil
+}
+
+// readWorkbook reads the sheet list and the date system of the workbook.
+func (wb *xlsxWorkbook) readWorkbook(name string, targets map[string]string) error {
+	rc, err := wb.open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "workbookPr":
+			v := attr(el, "date1904")
+			wb.date1904 = v == "1" || v == "true"
+		case "sheet":
+			// The relationship id is the only "id" attribute in a
+			// namespace; transitional and strict packages use different
+			// namespaces for it.
+			var id string
+			for _, a := range el.Attr {
+				if a.Name.Local == "id" && a.Name.Space != "" {
+					id = a.Value
+				}
+			}
+			wb.sheets = append(wb.sheets, xlsxSheet{name: attr(el, "name"), path: targets[id]})
+		}
+	}
+}
+
+// readSharedStrings decodes the shared strings table one item at a time
+// and spools the items to a temporary file. Exports often store IMEIs,
+// MSDINs and call times as text, so the table grows with the file; only
+// the offset of each item is kept in memory.
+func (wb *xlsxWorkbook) readSharedStrings(name string) (*sharedStrings, error) {
+	rc, err := wb.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	table, err := newSharedStrings()
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	// inText is set inside the t elements of an item, outside of the
+	// phonetic runs, which are not part of the value.
+	inText, inPhonetic := false, false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			table.Close()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "si":
+				text.Reset()
+			case "rPh":
+				inPhonetic = true
+			case "t":
+				inText = !inPhonetic
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "si":
+				if err := table.add(unescapeXLSX(text.String())); err != nil {
+					table.Close()
+					return nil, err
+				}
+			case "rPh":
+				inPhonetic = false
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(el)
+			}
+		}
+	}
+	if err := table.w.Flush(); err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// sharedStrings is a shared strings table spooled to a temporary file.
+// offsets holds the end of every item in the file.
+type sharedStrings struct {
+	file    *os.File
+	w       *bufio.Writer
+	offsets []int64
+	size    int64
+}
+
+func newSharedStrings() (*sharedStrings, error) {
+	file, err := os.CreateTemp("", "dgraph_imei-strings-*")
+	if err != nil {
+		return nil, err
+	}
+	return &sharedStrings{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+func (t *sharedStrings) add(s string) error {
+	n, err := t.w.WriteString(s)
+	t.size += int64(n)
+	t.offsets = append(t.offsets, t.size)
+	return err
+}
+
+// get returns item i, and false if the table has no such item.
+func (t *sharedStrings) get(i int) (string, bool, error) {
+	if t == nil || i < 0 || i >= len(t.offsets) {
+		return "", false, nil
+	}
+	var start int64
+	if i > 0 {
+		start = t.offsets[i-1]
+	}
+	buf := make([]byte, t.offsets[i]-start)
+	if _, err := t.file.ReadAt(buf, start); err != nil {
+		return "", false, fmt.Errorf("failed to read shared string %d: %w", i, err)
+	}
+	return string(buf), true, nil
+}
+
+func (t *sharedStrings) Close() error {
+	err := t.file.Close()
+	os.Remove(t.file.Name())
+	return err
+}
+
+// xlsxSheetReader streams the rows of a worksheet.
+type xlsxSheetReader struct {
+	rc      io.ReadCloser
+	dec     *xml.Decoder
+	strings *sharedStrings
+	done    bool
+}
+
+func (wb *xlsxWorkbook) openSheet(sheet xlsxSheet) (*xlsxSheetReader, error) {
+	rc, err := wb.open(sheet.path)
+	if err != nil {
+		return nil, fmt.Errorf("sheet %s: %w", sheet.name, err)
+	}
+	return &xlsxSheetReader{rc: rc, dec: xml.NewDecoder(rc), strings: wb.strings}, nil
+}
+
+// xlsxCell is a c element of a worksheet.
+type xlsxCell struct {
+	R  string    `xml:"r,attr"`
+	T  string    `xml:"t,attr"`
+	V  string    `xml:"v"`
+	F  *struct{} `xml:"f"`
+	IS *struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// value returns the raw value of the cell: the text of string cells and
+// the stored value of the others, like excelize with RawCellValue.
+func (c *xlsxCell) value(shared *sharedStrings) (string, error) {
+	switch c.T {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil {
+			return c.V, nil
+		}
+		s, ok, err := shared.get(i)
+		if !ok {
+			return c.V, err
+		}
+		return s, nil
+	case "inlineStr":
+		if c.IS == nil {
+			return c.V, nil
+		}
+		var b strings.Builder
+		b.WriteString(c.IS.T)
+		for _, r := range c.IS.R {
+			b.WriteString(r.T)
+		}
+		return unescapeXLSX(b.String()), nil
+	default:
+		return c.V, nil
+	}
+}
+
+// nextRow returns the number and the cells of the next row element, or
+// io.EOF at the end of the sheet data. num is 0 if the row has no number.
+func (s *xlsxSheetReader) nextRow() (num int, cells []string, err error) {
+	if s.done {
+		return 0, nil, io.EOF
+	}
+	for {
+		tok, err := s.dec.Token()
+		if err == io.EOF {
+			s.done = true
+			return 0, nil, io.EOF
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if el.Name.Local == "row" {
+				num, _ = strconv.Atoi(attr(el, "r"))
+				cells, err = s.readCells()
+				return num, cells, err
+			}
+		case xml.EndElement:
+			if el.Name.Local == "sheetData" {
+				s.done = true
+				return 0, nil, io.EOF
+			}
+		}
+	}
+}
+
+// readCells decodes the cells of the current row. Cells without a value
+// leave their column empty.
+func (s *xlsxSheetReader) readCells() ([]string, error) {
+	var cells []string
+	col := 0
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if el.Name.Local != "c" {
+				if err := s.dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var c xlsxCell
+			if err := s.dec.DecodeElement(&c, &el); err != nil {
+				return nil, err
+			}
+			col++
+			if c.R != "" {
+				if col, err = cellColumn(c.R); err != nil {
+					return nil, err
+				}
+			}
+			v, err := c.value(s.strings)
+			if err != nil {
+				return nil, err
+			}
+			if v == "" && c.F == nil {
+				continue
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			cells[col-1] = v
+		case xml.EndElement:
+			if el.Name.Local == "row" {
+				return cells, nil
+			}
+		}
+	}
+}
+
+func (s *xlsxSheetReader) Close() error {
+	return s.rc.Close()
+}
+
+// maxXLSXColumn is the last column of a worksheet, XFD.
+const maxXLSXColumn = 16384
+
+// cellColumn returns the 1-based column of a cell reference like "AB12".
+func cellColumn(ref string) (int, error) {
+	col := 0
+	for i := 0; i < len(ref); i++ {
+		ch := ref[i] | 0x20 // lower case
+		if ch < 'a' || ch > 'z' {
+			break
+		}
+		col = col*26 + int(ch-'a') + 1
+		if col > maxXLSXColumn {
+			break
+		}
+	}
+	if col == 0 || col > maxXLSXColumn {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col, nil
+}
+
+// unescapeXLSX decodes the _xHHHH_ escapes that OOXML uses for characters
+// XML cannot hold. _x005F_ escapes the underscore of a literal _xHHHH_.
+func unescapeXLSX(s string) string {
+	if !strings.Contains(s, "_x") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, ok := xlsxEscape(s[i:])
+		if !ok {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		i += 7
+		if r == '_' {
+			if _, ok := xlsxEscape(s[i:]); ok {
+				b.WriteString(s[i : i+7])
+				i += 7
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// xlsxEscape decodes an _xHHHH_ escape at the start of s.
+func xlsxEscape(s string) (rune, bool) {
+	if len(s) < 7 || s[0] != '_' || s[1] != 'x' || s[6] != '_' {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[2:6], 16, 16)
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
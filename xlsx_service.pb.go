@@ -28,6 +28,7 @@ type GetXlsxRequest struct {
 	unknownFields protoimpl.UnknownFields
 
 	FilePath string `protobuf:"bytes,1,opt,name=filePath,proto3" json:"filePath,omitempty"` // The path to the XLSX file on the server.
+	Offset   int64  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`    // The offset to resume an interrupted transfer from, 0 to start at the beginning.
 }
 
 func (x *GetXlsxRequest) Reset() {
@@ -69,14 +70,25 @@ func (x *GetXlsxRequest) GetFilePath() string {
 	return ""
 }
 
+func (x *GetXlsxRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
 // Data chunk of the XLSX file.
-// Each message contains a part of the file's data.
+// Each message contains a part of the file's data. The last message of a
+// transfer carries no data but the digest of the whole file.
 type XlsxDataChunk struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Chunk []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"` // A chunk of the XLSX file data.
+	Chunk     []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`          // A chunk of the XLSX file data.
+	Offset    int64  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`       // The offset of the chunk in the file.
+	TotalSize int64  `protobuf:"varint,3,opt,name=totalSize,proto3" json:"totalSize,omitempty"` // The size of the whole file.
+	Sha256    string `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`        // The hex SHA-256 digest of the whole file, in the last message only.
 }
 
 func (x *XlsxDataChunk) Reset() {
@@ -118,26 +130,727 @@ func (x *XlsxDataChunk) GetChunk() []byte {
 	return nil
 }
 
+func (x *XlsxDataChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *XlsxDataChunk) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+func (x *XlsxDataChunk) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+// Metadata of an uploaded file.
+type UploadMetadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FileName string `protobuf:"bytes,1,opt,name=fileName,proto3" json:"fileName,omitempty"` // The name of the file on the client. Its extension tells the file format.
+	Size     int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`        // The size of the file in bytes, checked by the server if not 0.
+}
+
+func (x *UploadMetadata) Reset() {
+	*x = UploadMetadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadMetadata) ProtoMessage() {}
+
+func (x *UploadMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadMetadata.ProtoReflect.Descriptor instead.
+func (*UploadMetadata) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UploadMetadata) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// Message of an upload stream. The first message carries the metadata and
+// every following message a chunk of the file data.
+type UploadXlsxRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Data:
+	//	*UploadXlsxRequest_Metadata
+	//	*UploadXlsxRequest_Chunk
+	Data isUploadXlsxRequest_Data `protobuf_oneof:"data"`
+}
+
+func (x *UploadXlsxRequest) Reset() {
+	*x = UploadXlsxRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadXlsxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadXlsxRequest) ProtoMessage() {}
+
+func (x *UploadXlsxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadXlsxRequest.ProtoReflect.Descriptor instead.
+func (*UploadXlsxRequest) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *UploadXlsxRequest) GetData() isUploadXlsxRequest_Data {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (x *UploadXlsxRequest) GetMetadata() *UploadMetadata {
+	if x, ok := x.GetData().(*UploadXlsxRequest_Metadata); ok {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *UploadXlsxRequest) GetChunk() []byte {
+	if x, ok := x.GetData().(*UploadXlsxRequest_Chunk); ok {
+		return x.Chunk
+	}
+	return nil
+}
+
+type isUploadXlsxRequest_Data interface {
+	isUploadXlsxRequest_Data()
+}
+
+type UploadXlsxRequest_Metadata struct {
+	Metadata *UploadMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"` // The metadata of the file, in the first message only.
+}
+
+type UploadXlsxRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // A chunk of the file data.
+}
+
+func (*UploadXlsxRequest_Metadata) isUploadXlsxRequest_Data() {}
+
+func (*UploadXlsxRequest_Chunk) isUploadXlsxRequest_Data() {}
+
+// Response to a completed upload.
+type UploadXlsxResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UploadId string `protobuf:"bytes,1,opt,name=uploadId,proto3" json:"uploadId,omitempty"` // Identifies the stored file. Pass it as the file path to ingest the file.
+	Size     int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`        // The number of bytes stored.
+}
+
+func (x *UploadXlsxResponse) Reset() {
+	*x = UploadXlsxResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadXlsxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadXlsxResponse) ProtoMessage() {}
+
+func (x *UploadXlsxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadXlsxResponse.ProtoReflect.Descriptor instead.
+func (*UploadXlsxResponse) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UploadXlsxResponse) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *UploadXlsxResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// Request message for parsing a file on the server.
+type ParseCallsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FilePath string `protobuf:"bytes,1,opt,name=filePath,proto3" json:"filePath,omitempty"` // The path or upload ID of the file on the server.
+	Format   string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`     // The file format: xlsx, csv, tsv, jsonl or parquet. Empty to tell it from the extension.
+}
+
+func (x *ParseCallsRequest) Reset() {
+	*x = ParseCallsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParseCallsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseCallsRequest) ProtoMessage() {}
+
+func (x *ParseCallsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseCallsRequest.ProtoReflect.Descriptor instead.
+func (*ParseCallsRequest) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ParseCallsRequest) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *ParseCallsRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+// A validated call.
+type CallRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msdin         string  `protobuf:"bytes,1,opt,name=msdin,proto3" json:"msdin,omitempty"`                 // The normalised MSDIN.
+	MsdinOriginal string  `protobuf:"bytes,2,opt,name=msdinOriginal,proto3" json:"msdinOriginal,omitempty"` // The MSDIN as written in the file, if it differs.
+	ImeiFrom      string  `protobuf:"bytes,3,opt,name=imeiFrom,proto3" json:"imeiFrom,omitempty"`
+	ImeiTo        string  `protobuf:"bytes,4,opt,name=imeiTo,proto3" json:"imeiTo,omitempty"`
+	Latitude      float64 `protobuf:"fixed64,5,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64 `protobuf:"fixed64,6,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Duration      float64 `protobuf:"fixed64,7,opt,name=duration,proto3" json:"duration,omitempty"` // The call duration in seconds.
+	CallTime      string  `protobuf:"bytes,8,opt,name=callTime,proto3" json:"callTime,omitempty"`   // The call time in RFC 3339 format.
+	CallKey       string  `protobuf:"bytes,9,opt,name=callKey,proto3" json:"callKey,omitempty"`     // Identifies the call independently of the file it was read from.
+}
+
+func (x *CallRecord) Reset() {
+	*x = CallRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CallRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallRecord) ProtoMessage() {}
+
+func (x *CallRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallRecord.ProtoReflect.Descriptor instead.
+func (*CallRecord) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CallRecord) GetMsdin() string {
+	if x != nil {
+		return x.Msdin
+	}
+	return ""
+}
+
+func (x *CallRecord) GetMsdinOriginal() string {
+	if x != nil {
+		return x.MsdinOriginal
+	}
+	return ""
+}
+
+func (x *CallRecord) GetImeiFrom() string {
+	if x != nil {
+		return x.ImeiFrom
+	}
+	return ""
+}
+
+func (x *CallRecord) GetImeiTo() string {
+	if x != nil {
+		return x.ImeiTo
+	}
+	return ""
+}
+
+func (x *CallRecord) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *CallRecord) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *CallRecord) GetDuration() float64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *CallRecord) GetCallTime() string {
+	if x != nil {
+		return x.CallTime
+	}
+	return ""
+}
+
+func (x *CallRecord) GetCallKey() string {
+	if x != nil {
+		return x.CallKey
+	}
+	return ""
+}
+
+// A row that failed validation.
+type RowRejection struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sheet  string `protobuf:"bytes,1,opt,name=sheet,proto3" json:"sheet,omitempty"`
+	Row    int32  `protobuf:"varint,2,opt,name=row,proto3" json:"row,omitempty"`
+	Column string `protobuf:"bytes,3,opt,name=column,proto3" json:"column,omitempty"` // The column holding the invalid value.
+	Value  string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	Reason string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"` // The machine readable reason, like invalid_imei.
+	Detail string `protobuf:"bytes,6,opt,name=detail,proto3" json:"detail,omitempty"`
+}
+
+func (x *RowRejection) Reset() {
+	*x = RowRejection{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RowRejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RowRejection) ProtoMessage() {}
+
+func (x *RowRejection) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RowRejection.ProtoReflect.Descriptor instead.
+func (*RowRejection) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RowRejection) GetSheet() string {
+	if x != nil {
+		return x.Sheet
+	}
+	return ""
+}
+
+func (x *RowRejection) GetRow() int32 {
+	if x != nil {
+		return x.Row
+	}
+	return 0
+}
+
+func (x *RowRejection) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *RowRejection) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *RowRejection) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RowRejection) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+// Summary of a parsed file, in the last message of a ParseCalls stream.
+type ParseSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Accepted int32 `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected int32 `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+}
+
+func (x *ParseSummary) Reset() {
+	*x = ParseSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParseSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseSummary) ProtoMessage() {}
+
+func (x *ParseSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseSummary.ProtoReflect.Descriptor instead.
+func (*ParseSummary) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ParseSummary) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *ParseSummary) GetRejected() int32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+// Message of a ParseCalls stream.
+type ParseCallsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Item:
+	//	*ParseCallsResponse_Call
+	//	*ParseCallsResponse_Rejection
+	//	*ParseCallsResponse_Summary
+	Item isParseCallsResponse_Item `protobuf_oneof:"item"`
+}
+
+func (x *ParseCallsResponse) Reset() {
+	*x = ParseCallsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_xlsx_service_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParseCallsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseCallsResponse) ProtoMessage() {}
+
+func (x *ParseCallsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xlsx_service_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseCallsResponse.ProtoReflect.Descriptor instead.
+func (*ParseCallsResponse) Descriptor() ([]byte, []int) {
+	return file_xlsx_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (m *ParseCallsResponse) GetItem() isParseCallsResponse_Item {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (x *ParseCallsResponse) GetCall() *CallRecord {
+	if x, ok := x.GetItem().(*ParseCallsResponse_Call); ok {
+		return x.Call
+	}
+	return nil
+}
+
+func (x *ParseCallsResponse) GetRejection() *RowRejection {
+	if x, ok := x.GetItem().(*ParseCallsResponse_Rejection); ok {
+		return x.Rejection
+	}
+	return nil
+}
+
+func (x *ParseCallsResponse) GetSummary() *ParseSummary {
+	if x, ok := x.GetItem().(*ParseCallsResponse_Summary); ok {
+		return x.Summary
+	}
+	return nil
+}
+
+type isParseCallsResponse_Item interface {
+	isParseCallsResponse_Item()
+}
+
+type ParseCallsResponse_Call struct {
+	Call *CallRecord `protobuf:"bytes,1,opt,name=call,proto3,oneof"`
+}
+
+type ParseCallsResponse_Rejection struct {
+	Rejection *RowRejection `protobuf:"bytes,2,opt,name=rejection,proto3,oneof"`
+}
+
+type ParseCallsResponse_Summary struct {
+	Summary *ParseSummary `protobuf:"bytes,3,opt,name=summary,proto3,oneof"`
+}
+
+func (*ParseCallsResponse_Call) isParseCallsResponse_Item() {}
+
+func (*ParseCallsResponse_Rejection) isParseCallsResponse_Item() {}
+
+func (*ParseCallsResponse_Summary) isParseCallsResponse_Item() {}
+
 var File_xlsx_service_proto protoreflect.FileDescriptor
 
 var file_xlsx_service_proto_rawDesc = []byte{
 	0x0a, 0x12, 0x78, 0x6c, 0x73, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0x22, 0x2c, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x22, 0x44, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75,
 	0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x74, 0x68, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x74, 0x68, 0x22,
-	0x25, 0x0a, 0x0d, 0x58, 0x6c, 0x73, 0x78, 0x44, 0x61, 0x74, 0x61, 0x43, 0x68, 0x75, 0x6e, 0x6b,
-	0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
-	0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x32, 0x57, 0x0a, 0x0b, 0x58, 0x6c, 0x73, 0x78, 0x53, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x48, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x58, 0x6c, 0x73, 0x78,
-	0x44, 0x61, 0x74, 0x61, 0x12, 0x1b, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x1a, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
-	0x58, 0x6c, 0x73, 0x78, 0x44, 0x61, 0x74, 0x61, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30, 0x01, 0x42,
-	0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x72,
-	0x63, 0x2f, 0x7a, 0x67, 0x6f, 0x72, 0x64, 0x61, 0x6e, 0x2d, 0x76, 0x76, 0x2f, 0x64, 0x67, 0x72,
-	0x61, 0x70, 0x68, 0x5f, 0x69, 0x6d, 0x65, 0x69, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x74, 0x68, 0x12,
+	0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x22, 0x73, 0x0a, 0x0d, 0x58, 0x6c, 0x73, 0x78, 0x44,
+	0x61, 0x74, 0x61, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e,
+	0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x16,
+	0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06,
+	0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x53,
+	0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c,
+	0x53, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x22, 0x40, 0x0a, 0x0e,
+	0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1a,
+	0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x6e,
+	0x0a, 0x11, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x39, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0x48, 0x00, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16,
+	0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52,
+	0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x44,
+	0x0a, 0x12, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x49, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x49, 0x64,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x22, 0x47, 0x0a, 0x11, 0x50, 0x61, 0x72, 0x73, 0x65, 0x43, 0x61, 0x6c,
+	0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x50, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x50, 0x61, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x22, 0x88, 0x02,
+	0x0a, 0x0a, 0x43, 0x61, 0x6c, 0x6c, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x6d, 0x73, 0x64, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x73, 0x64,
+	0x69, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x6d, 0x73, 0x64, 0x69, 0x6e, 0x4f, 0x72, 0x69, 0x67, 0x69,
+	0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6d, 0x73, 0x64, 0x69, 0x6e,
+	0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6d, 0x65, 0x69,
+	0x46, 0x72, 0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6d, 0x65, 0x69,
+	0x46, 0x72, 0x6f, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6d, 0x65, 0x69, 0x54, 0x6f, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x6d, 0x65, 0x69, 0x54, 0x6f, 0x12, 0x1a, 0x0a, 0x08,
+	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08,
+	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x6e,
+	0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x61, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x61, 0x6c, 0x6c, 0x4b, 0x65, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x61, 0x6c, 0x6c, 0x4b, 0x65, 0x79, 0x22, 0x94, 0x01, 0x0a, 0x0c, 0x52, 0x6f, 0x77,
+	0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x68, 0x65,
+	0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x68, 0x65, 0x65, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x72, 0x6f, 0x77, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x72, 0x6f,
+	0x77, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x65, 0x74, 0x61, 0x69,
+	0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x22,
+	0x46, 0x0a, 0x0c, 0x50, 0x61, 0x72, 0x73, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12,
+	0x1a, 0x0a, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x72,
+	0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x72,
+	0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x64, 0x22, 0xbd, 0x01, 0x0a, 0x12, 0x50, 0x61, 0x72, 0x73,
+	0x65, 0x43, 0x61, 0x6c, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d,
+	0x0a, 0x04, 0x63, 0x61, 0x6c, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x78,
+	0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x43, 0x61, 0x6c, 0x6c, 0x52,
+	0x65, 0x63, 0x6f, 0x72, 0x64, 0x48, 0x00, 0x52, 0x04, 0x63, 0x61, 0x6c, 0x6c, 0x12, 0x39, 0x0a,
+	0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x19, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x52,
+	0x6f, 0x77, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x72,
+	0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x35, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d,
+	0x61, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x78, 0x6c, 0x73, 0x78,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x72, 0x73, 0x65, 0x53, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x42,
+	0x06, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x32, 0xf9, 0x01, 0x0a, 0x0b, 0x58, 0x6c, 0x73, 0x78,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x48, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x58, 0x6c,
+	0x73, 0x78, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1b, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x58, 0x6c, 0x73, 0x78, 0x44, 0x61, 0x74, 0x61, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30,
+	0x01, 0x12, 0x4f, 0x0a, 0x0a, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x58, 0x6c, 0x73, 0x78, 0x12,
+	0x1e, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1f, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x58, 0x6c, 0x73, 0x78, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x28, 0x01, 0x12, 0x4f, 0x0a, 0x0a, 0x50, 0x61, 0x72, 0x73, 0x65, 0x43, 0x61, 0x6c, 0x6c, 0x73,
+	0x12, 0x1e, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50,
+	0x61, 0x72, 0x73, 0x65, 0x43, 0x61, 0x6c, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1f, 0x2e, 0x78, 0x6c, 0x73, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50,
+	0x61, 0x72, 0x73, 0x65, 0x43, 0x61, 0x6c, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x30, 0x01, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x73, 0x72, 0x63, 0x2f, 0x7a, 0x67, 0x6f, 0x72, 0x64, 0x61, 0x6e, 0x2d, 0x76, 0x76,
+	0x2f, 0x64, 0x67, 0x72, 0x61, 0x70, 0x68, 0x5f, 0x69, 0x6d, 0x65, 0x69, 0x2f, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -152,19 +865,35 @@ func file_xlsx_service_proto_rawDescGZIP() []byte {
 	return file_xlsx_service_proto_rawDescData
 }
 
-var file_xlsx_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_xlsx_service_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_xlsx_service_proto_goTypes = []interface{}{
-	(*GetXlsxRequest)(nil), // 0: xlsxservice.GetXlsxRequest
-	(*XlsxDataChunk)(nil),  // 1: xlsxservice.XlsxDataChunk
+	(*GetXlsxRequest)(nil),     // 0: xlsxservice.GetXlsxRequest
+	(*XlsxDataChunk)(nil),      // 1: xlsxservice.XlsxDataChunk
+	(*UploadMetadata)(nil),     // 2: xlsxservice.UploadMetadata
+	(*UploadXlsxRequest)(nil),  // 3: xlsxservice.UploadXlsxRequest
+	(*UploadXlsxResponse)(nil), // 4: xlsxservice.UploadXlsxResponse
+	(*ParseCallsRequest)(nil),  // 5: xlsxservice.ParseCallsRequest
+	(*CallRecord)(nil),         // 6: xlsxservice.CallRecord
+	(*RowRejection)(nil),       // 7: xlsxservice.RowRejection
+	(*ParseSummary)(nil),       // 8: xlsxservice.ParseSummary
+	(*ParseCallsResponse)(nil), // 9: xlsxservice.ParseCallsResponse
 }
 var file_xlsx_service_proto_depIdxs = []int32{
-	0, // 0: xlsxservice.XlsxService.GetXlsxData:input_type -> xlsxservice.GetXlsxRequest
-	1, // 1: xlsxservice.XlsxService.GetXlsxData:output_type -> xlsxservice.XlsxDataChunk
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2, // 0: xlsxservice.UploadXlsxRequest.metadata:type_name -> xlsxservice.UploadMetadata
+	6, // 1: xlsxservice.ParseCallsResponse.call:type_name -> xlsxservice.CallRecord
+	7, // 2: xlsxservice.ParseCallsResponse.rejection:type_name -> xlsxservice.RowRejection
+	8, // 3: xlsxservice.ParseCallsResponse.summary:type_name -> xlsxservice.ParseSummary
+	0, // 4: xlsxservice.XlsxService.GetXlsxData:input_type -> xlsxservice.GetXlsxRequest
+	3, // 5: xlsxservice.XlsxService.UploadXlsx:input_type -> xlsxservice.UploadXlsxRequest
+	5, // 6: xlsxservice.XlsxService.ParseCalls:input_type -> xlsxservice.ParseCallsRequest
+	1, // 7: xlsxservice.XlsxService.GetXlsxData:output_type -> xlsxservice.XlsxDataChunk
+	4, // 8: xlsxservice.XlsxService.UploadXlsx:output_type -> xlsxservice.UploadXlsxResponse
+	9, // 9: xlsxservice.XlsxService.ParseCalls:output_type -> xlsxservice.ParseCallsResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_xlsx_service_proto_init() }
@@ -197,6 +926,111 @@ func file_xlsx_service_proto_init() {
 				return nil
 			}
 		}
+		file_xlsx_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadMetadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadXlsxRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadXlsxResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ParseCallsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CallRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RowRejection); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ParseSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_xlsx_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ParseCallsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_xlsx_service_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*UploadXlsxRequest_Metadata)(nil),
+		(*UploadXlsxRequest_Chunk)(nil),
+	}
+	file_xlsx_service_proto_msgTypes[9].OneofWrappers = []interface{}{
+		(*ParseCallsResponse_Call)(nil),
+		(*ParseCallsResponse_Rejection)(nil),
+		(*ParseCallsResponse_Summary)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -204,7 +1038,7 @@ func file_xlsx_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_xlsx_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	XlsxService_GetXlsxData_FullMethodName = "/xlsxservice.XlsxService/GetXlsxData"
+	XlsxService_UploadXlsx_FullMethodName  = "/xlsxservice.XlsxService/UploadXlsx"
+	XlsxService_ParseCalls_FullMethodName  = "/xlsxservice.XlsxService/ParseCalls"
 )
 
 // XlsxServiceClient is the client API for XlsxService service.
@@ -28,6 +30,13 @@ const (
 type XlsxServiceClient interface {
 	// Requests the XLSX data for a given file path and receives it in chunks.
 	GetXlsxData(ctx context.Context, in *GetXlsxRequest, opts ...grpc.CallOption) (XlsxService_GetXlsxDataClient, error)
+	// Uploads a file to the server in chunks. The first message carries the
+	// file metadata, the following ones the file data. The returned upload ID
+	// can be used as the file path of GetXlsxData.
+	UploadXlsx(ctx context.Context, opts ...grpc.CallOption) (XlsxService_UploadXlsxClient, error)
+	// Parses a file on the server with the same validation as the client and
+	// streams the accepted calls and the rejected rows, followed by a summary.
+	ParseCalls(ctx context.Context, in *ParseCallsRequest, opts ...grpc.CallOption) (XlsxService_ParseCallsClient, error)
 }
 
 type xlsxServiceClient struct {
@@ -70,12 +79,85 @@ func (x *xlsxServiceGetXlsxDataClient) Recv() (*XlsxDataChunk, error) {
 	return m, nil
 }
 
+func (c *xlsxServiceClient) UploadXlsx(ctx context.Context, opts ...grpc.CallOption) (XlsxService_UploadXlsxClient, error) {
+	stream, err := c.cc.NewStream(ctx, &XlsxService_ServiceDesc.Streams[1], XlsxService_UploadXlsx_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxServiceUploadXlsxClient{stream}
+	return x, nil
+}
+
+type XlsxService_UploadXlsxClient interface {
+	Send(*UploadXlsxRequest) error
+	CloseAndRecv() (*UploadXlsxResponse, error)
+	grpc.ClientStream
+}
+
+type xlsxServiceUploadXlsxClient struct {
+	grpc.ClientStream
+}
+
+func (x *xlsxServiceUploadXlsxClient) Send(m *UploadXlsxRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *xlsxServiceUploadXlsxClient) CloseAndRecv() (*UploadXlsxResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadXlsxResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *xlsxServiceClient) ParseCalls(ctx context.Context, in *ParseCallsRequest, opts ...grpc.CallOption) (XlsxService_ParseCallsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &XlsxService_ServiceDesc.Streams[2], XlsxService_ParseCalls_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxServiceParseCallsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type XlsxService_ParseCallsClient interface {
+	Recv() (*ParseCallsResponse, error)
+	grpc.ClientStream
+}
+
+type xlsxServiceParseCallsClient struct {
+	grpc.ClientStream
+}
+
+func (x *xlsxServiceParseCallsClient) Recv() (*ParseCallsResponse, error) {
+	m := new(ParseCallsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // XlsxServiceServer is the server API for XlsxService service.
 // All implementations must embed UnimplementedXlsxServiceServer
 // for forward compatibility
 type XlsxServiceServer interface {
 	// Requests the XLSX data for a given file path and receives it in chunks.
 	GetXlsxData(*GetXlsxRequest, XlsxService_GetXlsxDataServer) error
+	// Uploads a file to the server in chunks. The first message carries the
+	// file metadata, the following ones the file data. The returned upload ID
+	// can be used as the file path of GetXlsxData.
+	UploadXlsx(XlsxService_UploadXlsxServer) error
+	// Parses a file on the server with the same validation as the client and
+	// streams the accepted calls and the rejected rows, followed by a summary.
+	ParseCalls(*ParseCallsRequest, XlsxService_ParseCallsServer) error
 	mustEmbedUnimplementedXlsxServiceServer()
 }
 
@@ -86,6 +168,12 @@ type UnimplementedXlsxServiceServer struct {
 func (UnimplementedXlsxServiceServer) GetXlsxData(*GetXlsxRequest, XlsxService_GetXlsxDataServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetXlsxData not implemented")
 }
+func (UnimplementedXlsxServiceServer) UploadXlsx(XlsxService_UploadXlsxServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadXlsx not implemented")
+}
+func (UnimplementedXlsxServiceServer) ParseCalls(*ParseCallsRequest, XlsxService_ParseCallsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ParseCalls not implemented")
+}
 func (UnimplementedXlsxServiceServer) mustEmbedUnimplementedXlsxServiceServer() {}
 
 // UnsafeXlsxServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +208,53 @@ func (x *xlsxServiceGetXlsxDataServer) Send(m *XlsxDataChunk) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _XlsxService_UploadXlsx_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(XlsxServiceServer).UploadXlsx(&xlsxServiceUploadXlsxServer{stream})
+}
+
+type XlsxService_UploadXlsxServer interface {
+	SendAndClose(*UploadXlsxResponse) error
+	Recv() (*UploadXlsxRequest, error)
+	grpc.ServerStream
+}
+
+type xlsxServiceUploadXlsxServer struct {
+	grpc.ServerStream
+}
+
+func (x *xlsxServiceUploadXlsxServer) SendAndClose(m *UploadXlsxResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *xlsxServiceUploadXlsxServer) Recv() (*UploadXlsxRequest, error) {
+	m := new(UploadXlsxRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _XlsxService_ParseCalls_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ParseCallsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(XlsxServiceServer).ParseCalls(m, &xlsxServiceParseCallsServer{stream})
+}
+
+type XlsxService_ParseCallsServer interface {
+	Send(*ParseCallsResponse) error
+	grpc.ServerStream
+}
+
+type xlsxServiceParseCallsServer struct {
+	grpc.ServerStream
+}
+
+func (x *xlsxServiceParseCallsServer) Send(m *ParseCallsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // XlsxService_ServiceDesc is the grpc.ServiceDesc for XlsxService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -133,6 +268,16 @@ var XlsxService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _XlsxService_GetXlsxData_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "UploadXlsx",
+			Handler:       _XlsxService_UploadXlsx_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ParseCalls",
+			Handler:       _XlsxService_ParseCalls_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "xlsx_service.proto",
 }